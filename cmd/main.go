@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"mpris-timer/internal/core"
 	"mpris-timer/internal/ui"
@@ -10,9 +12,15 @@ import (
 	"runtime/pprof"
 	"slices"
 	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && core.IsSubcommand(os.Args[1]) {
+		os.Exit(core.RunSubcommand(os.Args[1:]))
+	}
+
 	stopProf := profile()
 	if stopProf != nil {
 		defer stopProf()
@@ -21,77 +29,398 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	glibDone := core.RegisterApp(ctx)
 	core.LoadPrefs()
 	core.LoadFlags()
+	// before GTK and the audio start their threads, setenv isn't safe after that
+	core.SetAudioSinkEnv()
+
+	glibDone := core.RegisterApp(ctx)
+	core.MigrateDirs()
 	go core.InitCache()
 
-	if core.Overrides.Sound {
-		go func() { _ = core.LoadSound() }()
+	if core.Overrides.Sound || core.Overrides.Daemon {
+		go func() {
+			if err := core.LoadSound(); err != nil {
+				log.Printf("load sound: %v, using the default one", err)
+			}
+		}()
+	}
+
+	if core.Overrides.UseUI && (core.Overrides.Duration > 0 || core.Overrides.Stopwatch) {
+		log.Fatalf("UI can't be used with -start or -stopwatch")
 	}
 
-	if core.Overrides.UseUI && core.Overrides.Duration > 0 {
-		log.Fatalf("UI can't be used with -start")
+	if core.Overrides.UseUI && core.Overrides.Daemon {
+		log.Fatalf("UI can't be used with -daemon")
+	}
+
+	if core.Overrides.RestoreId != "" && (core.Overrides.UseUI || core.Overrides.Daemon || core.Overrides.Duration > 0 || core.Overrides.Stopwatch) {
+		log.Fatalf("-restore can't be used with -ui, -daemon, -start or -stopwatch")
+	}
+
+	if core.Overrides.Daemon {
+		log.Println("daemon requested")
+		<-glibDone
+		runDaemon()
+		return
+	}
+
+	<-glibDone
+	if core.Overrides.RestoreId == "" && core.ClaimRestore() {
+		restorePending(func(saved core.SavedTimer) error {
+			return core.RestoreDetached(saved.Id)
+		})
 	}
 
 	// UI by default
-	if !core.Overrides.UseUI && core.Overrides.Duration == 0 {
+	if !core.Overrides.UseUI && core.Overrides.Duration == 0 && !core.Overrides.Stopwatch && core.Overrides.RestoreId == "" {
 		core.Overrides.UseUI = true
 	}
 
 	if core.Overrides.UseUI {
 		log.Println("UI requested")
-		<-glibDone
 		ui.Init()
 	}
 
-	timer, err := core.NewTimerPlayer(core.Overrides.Duration, core.Overrides.Title)
+	if core.Overrides.Duration > 0 || core.Overrides.Stopwatch {
+		id, err := core.HandOff(core.Overrides.Duration, core.Overrides.Title, core.DefaultTimerOptions())
+		if err == nil {
+			log.Printf("timer %s handed off to the daemon", id)
+			return
+		}
+
+		if !errors.Is(err, core.ErrNoDaemon) {
+			log.Printf("hand off to the daemon: %v, starting a separate timer", err)
+		}
+	}
+
+	timer, err := newTimer()
 	if err != nil {
 		log.Fatalf("create timer: %v", err)
 	}
 
 	log.Printf("timer requested, duration = %d sec", core.Overrides.Duration)
-	if err = timer.Start(); err != nil {
+	timer.OnPhaseEnd = phaseEnd
+	timer.OnWarning = warn
+	if err = startTimer(timer); err != nil {
 		log.Fatalf("start timer: %v", err)
 	}
 
+	// the bus name of a ringing timer is released in the background, wait for it before exiting
+	defer func() {
+		timer.Silence()
+		<-timer.Released()
+		log.Println("timer released")
+	}()
+
 	if (!core.IsGnome && !core.IsPlasma) || core.Overrides.ForceTrayIcon {
 		go ui.CreateTrayIcon(timer)
 	}
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-
-	select {
-	case <-timer.Done:
-		log.Println("timer done")
-		wg := sync.WaitGroup{}
-
-		if core.Overrides.Notify {
-			wg.Add(1)
-			log.Printf("notification requested")
-			go func() {
-				ui.Notify(timer.Name, core.Overrides.Text)
-				wg.Done()
-			}()
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-timer.Done:
+			if timer.IsCancelled {
+				log.Println("timer cancelled")
+				return
+			}
+
+			log.Println("timer done")
+
+			// the notification may wait for an answer for a long time
+			answer := make(chan bool, 1)
+			go func() { answer <- finish(timer) }()
+
+			var rearmed bool
+			select {
+			case rearmed = <-answer:
+			case <-sigChan:
+				return
+			}
+
+			if !rearmed {
+				return
+			}
+
+			if err = startTimer(timer); err != nil {
+				log.Fatalf("start timer: %v", err)
+			}
+		case sig := <-sigChan:
+			// the session is ending, keep the timer to restore it on the next launch
+			if sig != os.Interrupt {
+				timer.Detach()
+				<-timer.Done
+				log.Println("timer detached")
+				return
+			}
+
+			timer.Cancel()
+			<-timer.Done
+			log.Println("timer cancelled")
+			return
 		}
+	}
+}
 
-		if core.Overrides.Sound {
-			wg.Add(1)
-			log.Printf("sound requested")
-			go func() {
-				err = core.PlaySound()
-				if err != nil {
-					log.Printf("playing sound: %v", err)
-				}
-				wg.Done()
-			}()
+// startTimer starts a standalone timer along with its control interface
+func startTimer(timer *core.TimerPlayer) error {
+	if err := timer.Start(); err != nil {
+		return err
+	}
+
+	if err := core.ExportControl(timer); err != nil {
+		log.Printf("export control interface: %v", err)
+	}
+
+	return nil
+}
+
+func runDaemon() {
+	daemon := core.NewDaemon()
+	daemon.OnFinish = func(timer *core.TimerPlayer) {
+		if !finish(timer) {
+			return
+		}
+
+		if err := daemon.AddTimer(timer); err != nil {
+			log.Printf("start timer: %v", err)
+		}
+	}
+	daemon.OnPhaseEnd = phaseEnd
+	daemon.OnWarning = warn
+
+	if err := daemon.Start(); err != nil {
+		log.Fatalf("start daemon: %v", err)
+	}
+
+	if core.Overrides.Duration > 0 || core.Overrides.Stopwatch {
+		timer, err := newTimer()
+		if err != nil {
+			log.Fatalf("create timer: %v", err)
+		}
+
+		if err = daemon.AddTimer(timer); err != nil {
+			log.Fatalf("start timer: %v", err)
+		}
+	}
+
+	if core.ClaimRestore() {
+		restorePending(func(saved core.SavedTimer) error {
+			timer, err := core.RestoreTimer(saved)
+			if err != nil {
+				return err
+			}
+
+			return daemon.AddTimer(timer)
+		})
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	if sig := <-sigChan; sig != os.Interrupt {
+		log.Println("detaching daemon")
+		daemon.Detach()
+		return
+	}
+
+	log.Println("stopping daemon")
+	daemon.Destroy()
+}
+
+// restorePending reports the saved timers that expired meanwhile as missed,
+// the rest are passed to restore unless the user prefers to do that by hand
+func restorePending(restore func(core.SavedTimer) error) {
+	for _, saved := range core.PendingTimers() {
+		if saved.IsExpired() {
+			log.Printf("timer %s expired at %s", saved.Id, saved.End().Format(time.DateTime))
+			if saved.Options.Notify {
+				ui.Notify(saved.Title, fmt.Sprintf("%s (missed, was due at %s)", saved.Options.Text, saved.End().Format(time.TimeOnly)), ui.UrgencyCritical)
+			}
+
+			core.ForgetTimer(saved.Id)
+			continue
 		}
 
-		wg.Wait()
-	case <-sigChan:
-		timer.Destroy()
+		if !core.UserPrefs.RestoreTimers {
+			continue
+		}
+
+		log.Printf("restoring timer %s", saved.Id)
+		if err := restore(saved); err != nil {
+			log.Printf("restore timer %s: %v", saved.Id, err)
+		}
+	}
+}
+
+func newTimer() (*core.TimerPlayer, error) {
+	if core.Overrides.RestoreId != "" {
+		saved, err := core.LoadSavedTimer(core.Overrides.RestoreId)
+		if err != nil {
+			return nil, err
+		}
+
+		return core.RestoreTimer(saved)
+	}
+
+	if core.Overrides.Stopwatch {
+		return core.NewStopwatchPlayer(core.Overrides.Title)
+	}
+
+	if len(core.Overrides.Sequence) > 0 {
+		return core.NewSequencePlayer(core.Overrides.Sequence, core.Overrides.Title)
+	}
+
+	if !core.Overrides.AlarmAt.IsZero() {
+		return core.NewAlarmPlayer(core.Overrides.AlarmAt, core.Overrides.Title)
 	}
+
+	return core.NewTimerPlayer(core.Overrides.Duration, core.Overrides.Title)
+}
+
+// finish runs the notification and the sound, blocks until both are done.
+// Reports whether the timer was rearmed from the notification, Start it again then.
+func finish(timer *core.TimerPlayer) bool {
+	text := timer.Options.Text
+	if timer.IsMissed {
+		text += " (missed while suspended)"
+	}
+
+	// GNOME shows 3 buttons at most, +1 min and −1 min are offered while the timer runs, see adjust
+	snooze := ui.NotifyAction{Id: "snooze", Label: "Snooze " + formatSnooze(core.Overrides.Snooze)}
+	restart := ui.NotifyAction{Id: "restart", Label: "Restart"}
+	dismiss := ui.NotifyAction{Id: "dismiss", Label: "Dismiss"}
+
+	switch ring(timer, text, snooze, restart, dismiss) {
+	case snooze.Id:
+		log.Printf("timer %s snoozed for %s", timer.Id, core.Overrides.Snooze)
+		timer.Rearm(core.Overrides.Snooze)
+		return true
+	case restart.Id:
+		log.Printf("timer %s restarted", timer.Id)
+		timer.Restart()
+		return true
+	}
+
+	return false
+}
+
+func formatSnooze(d time.Duration) string {
+	if d%time.Minute == 0 {
+		return fmt.Sprintf("%d min", d/time.Minute)
+	}
+
+	return core.FormatDuration(d)
+}
+
+// the buttons of the notifications of a running timer
+var (
+	addMinute    = ui.NotifyAction{Id: "add-minute", Label: "+1 min"}
+	removeMinute = ui.NotifyAction{Id: "remove-minute", Label: "−1 min"}
+)
+
+// phaseEnd announces the next phase of a sequence
+func phaseEnd(timer *core.TimerPlayer, ended core.Segment) {
+	text := fmt.Sprintf("Next: %s", timer.PhaseTitle())
+	if ended.Name != "" {
+		text = fmt.Sprintf("%s is over, next: %s", ended.Name, timer.PhaseTitle())
+	}
+
+	adjust(timer, ring(timer, text, addMinute, removeMinute))
+}
+
+// warn announces the time left, the timer keeps running
+func warn(timer *core.TimerPlayer, warning core.Warning, left time.Duration) {
+	text := fmt.Sprintf("%s left", core.FormatDuration(left.Round(time.Second)))
+	if timer.Options.Notify && warning.Notify {
+		go func() {
+			adjust(timer, ui.NotifyWithActions(timer.Name, text, ui.UrgencyNormal, []ui.NotifyAction{addMinute, removeMinute}))
+		}()
+	}
+
+	if timer.Options.Sound && warning.Sound {
+		if err := core.PlayWarningSound(); err != nil {
+			log.Printf("playing warning sound: %v", err)
+		}
+	}
+}
+
+// adjust extends or shortens the timer as answered, unless it's over by then
+func adjust(timer *core.TimerPlayer, answer string) {
+	var d time.Duration
+	switch answer {
+	case addMinute.Id:
+		d = time.Minute
+	case removeMinute.Id:
+		d = -time.Minute
+	default:
+		return
+	}
+
+	if timer.IsFinished || timer.IsCancelled {
+		log.Printf("timer %s is over, not adjusted", timer.Id)
+		return
+	}
+
+	if err := timer.AddTime(d); err != nil {
+		log.Printf("adjust timer %s: %v", timer.Id, err)
+		return
+	}
+
+	log.Printf("timer %s adjusted by %s", timer.Id, d)
+}
+
+// ring returns the id of the notification action clicked, if any.
+// A finished timer may ring until the notification is answered, see core.Ring.
+func ring(timer *core.TimerPlayer, text string, actions ...ui.NotifyAction) string {
+	wg := sync.WaitGroup{}
+
+	// only the end of the timer is critical, not the end of a phase
+	urgency := ui.UrgencyNormal
+	if timer.IsFinished {
+		urgency = ui.UrgencyCritical
+	}
+
+	answer := ""
+	if timer.Options.Notify {
+		wg.Add(1)
+		log.Printf("notification requested")
+		go func() {
+			if len(actions) > 0 {
+				answer = ui.NotifyWithActions(timer.Name, text, urgency, actions)
+			} else {
+				ui.Notify(timer.Name, text, urgency)
+			}
+
+			if timer.IsFinished {
+				timer.Silence()
+			}
+			wg.Done()
+		}()
+	}
+
+	if timer.Options.Sound {
+		wg.Add(1)
+		log.Printf("sound requested")
+		go func() {
+			var err error
+			if timer.IsFinished && core.Overrides.Ring {
+				err = core.Ring(timer)
+			} else {
+				err = core.PlaySound()
+			}
+
+			if err != nil {
+				log.Printf("playing sound: %v", err)
+			}
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+	return answer
 }
 
 func profile() (cancel func()) {
@@ -0,0 +1,344 @@
+package core
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cacheMaxAge is how long a file may stay unused
+	cacheMaxAge = 30 * 24 * time.Hour
+	// staleColorAge is how long the files of another color are kept, the color was likely changed
+	staleColorAge = 7 * 24 * time.Hour
+	// cacheLowWater is the share of the limit the cache is evicted down to
+	cacheLowWater = 0.9
+)
+
+var (
+	// cacheBytes is the size of the cache
+	cacheBytes atomic.Int64
+	evicting   atomic.Bool
+	evictions  sync.WaitGroup
+	// sessionStart tells the files used in this session apart, their mtime is updated once
+	sessionStart = time.Now()
+)
+
+type cacheEntry struct {
+	size int64
+	used atomic.Int64 // unix nanoseconds
+}
+
+// fileCache remembers the rendered files, so the disk is only checked once for each
+type fileCache struct {
+	mu      sync.RWMutex
+	loaded  bool
+	entries map[string]*cacheEntry
+	checked atomic.Int64 // unix nanoseconds
+}
+
+func newFileCache() *fileCache {
+	return &fileCache{entries: make(map[string]*cacheEntry)}
+}
+
+// has reports false until the cache is loaded, check the disk then
+func (c *fileCache) has(filename string) bool {
+	c.mu.RLock()
+	entry, ok := c.entries[filename]
+	loaded := c.loaded
+	c.mu.RUnlock()
+
+	if !loaded || !ok {
+		return false
+	}
+
+	if !c.dirExists(filepath.Dir(filename)) {
+		c.reset()
+		return false
+	}
+
+	entry.use(filename)
+	return true
+}
+
+// dirExists is checked once a second at most, another process may have cleared the cache
+func (c *fileCache) dirExists(dir string) bool {
+	now := time.Now().UnixNano()
+	if now-c.checked.Load() < int64(time.Second) {
+		return true
+	}
+
+	c.checked.Store(now)
+	return fileExists(dir)
+}
+
+// adopt adds a file already on disk, false if there's none. The file is checked under the lock,
+// so an eviction can't remove it in between.
+func (c *fileCache) adopt(filename string) bool {
+	c.mu.Lock()
+	info, err := os.Stat(filename)
+	if err == nil {
+		c.insert(filename, info.Size(), info.ModTime())
+	}
+	c.mu.Unlock()
+
+	c.checkLimit()
+	return err == nil
+}
+
+// write adds the file with the data, written under the lock like adopt
+func (c *fileCache) write(filename string, data []byte) error {
+	c.mu.Lock()
+	err := os.WriteFile(filename, data, 0644)
+	if err == nil {
+		c.insert(filename, int64(len(data)), time.Now())
+	}
+	c.mu.Unlock()
+
+	c.checkLimit()
+	return err
+}
+
+// insert expects the lock to be held
+func (c *fileCache) insert(filename string, size int64, used time.Time) {
+	if _, ok := c.entries[filename]; ok {
+		return
+	}
+
+	entry := &cacheEntry{size: size}
+	entry.used.Store(used.UnixNano())
+	c.entries[filename] = entry
+	cacheBytes.Add(size)
+}
+
+// checkLimit starts the eviction if the cache has grown over the limit, FlushCache waits for it
+func (c *fileCache) checkLimit() {
+	if limit := cacheLimit(); limit > 0 && cacheBytes.Load() > limit {
+		evictions.Add(1)
+		go func() {
+			defer evictions.Done()
+			EvictCache()
+		}()
+	}
+}
+
+// remove deletes the file under the lock, unless it's gone from the cache already
+func (c *fileCache) remove(filename string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[filename]
+	if !ok {
+		return
+	}
+
+	delete(c.entries, filename)
+	cacheBytes.Add(-entry.size)
+	if err := os.Remove(filename); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("evict cache: %v", err)
+	}
+}
+
+func (c *fileCache) setLoaded() {
+	c.mu.Lock()
+	c.loaded = true
+	c.mu.Unlock()
+}
+
+func (c *fileCache) reset() {
+	c.mu.Lock()
+	for _, entry := range c.entries {
+		cacheBytes.Add(-entry.size)
+	}
+	c.entries = make(map[string]*cacheEntry)
+	c.mu.Unlock()
+}
+
+// use updates the mtime on the first use in a session, so the next sessions know it's needed
+func (e *cacheEntry) use(filename string) {
+	now := time.Now()
+	if e.used.Swap(now.UnixNano()) < sessionStart.UnixNano() {
+		_ = os.Chtimes(filename, time.Time{}, now)
+	}
+}
+
+type cachedFile struct {
+	filename string
+	size     int64
+	used     time.Time
+	owner    *fileCache
+}
+
+func (c *fileCache) files() []cachedFile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	files := make([]cachedFile, 0, len(c.entries))
+	for filename, entry := range c.entries {
+		files = append(files, cachedFile{
+			filename: filename,
+			size:     entry.size,
+			used:     time.Unix(0, entry.used.Load()),
+			owner:    c,
+		})
+	}
+
+	return files
+}
+
+// cacheLimit is in bytes, 0 is no limit
+func cacheLimit() int64 {
+	return int64(Overrides.CacheSize) << 20
+}
+
+// EvictCache removes the files unused for long and those of the colors no longer in use,
+// then the least recently used ones until the cache fits the limit
+func EvictCache() {
+	if !evicting.CompareAndSwap(false, true) {
+		return
+	}
+	defer evicting.Store(false)
+
+	files := cache.files()
+
+	// a color is in use if any of its files is
+	colorUsed := map[string]time.Time{}
+	for _, file := range files {
+		dir := filepath.Dir(file.filename)
+		if file.used.After(colorUsed[dir]) {
+			colorUsed[dir] = file.used
+		}
+	}
+
+	current := colorCacheDir()
+	kept := files[:0]
+	for _, file := range files {
+		dir := filepath.Dir(file.filename)
+		if time.Since(file.used) > cacheMaxAge || (dir != current && time.Since(colorUsed[dir]) > staleColorAge) {
+			file.owner.remove(file.filename)
+			continue
+		}
+
+		kept = append(kept, file)
+	}
+
+	if limit := cacheLimit(); limit > 0 && cacheBytes.Load() > limit {
+		slices.SortFunc(kept, func(a, b cachedFile) int { return a.used.Compare(b.used) })
+		for _, file := range kept {
+			if cacheBytes.Load() <= int64(float64(limit)*cacheLowWater) {
+				break
+			}
+
+			file.owner.remove(file.filename)
+		}
+	}
+
+	removeEmptyDirs(CacheDir)
+}
+
+// removeEmptyDirs removes the directories of the colors that are all gone
+func removeEmptyDirs(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			// fails unless empty
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// colorCacheDir is where the files of the current color go
+func colorCacheDir() string {
+	return filepath.Join(CacheDir, strings.ToUpper(strings.Replace(Overrides.Color, "#", "", 1)))
+}
+
+// CacheColorStats is a color directory of the cache
+type CacheColorStats struct {
+	Color    string    `json:"color"`
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+type CacheStats struct {
+	Dir    string            `json:"dir"`
+	Limit  int64             `json:"limit"`
+	Files  int               `json:"files"`
+	Bytes  int64             `json:"bytes"`
+	Colors []CacheColorStats `json:"colors"`
+}
+
+// ReadCacheStats looks at the disk, the cache of other processes is counted as well
+func ReadCacheStats() (CacheStats, error) {
+	stats := CacheStats{Dir: CacheDir, Limit: cacheLimit()}
+	colors := map[string]*CacheColorStats{}
+	err := filepath.WalkDir(CacheDir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		color := filepath.Base(filepath.Dir(filename))
+		if colors[color] == nil {
+			colors[color] = &CacheColorStats{Color: color}
+		}
+
+		colors[color].Files++
+		colors[color].Bytes += info.Size()
+		if info.ModTime().After(colors[color].LastUsed) {
+			colors[color].LastUsed = info.ModTime()
+		}
+
+		stats.Files++
+		stats.Bytes += info.Size()
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return stats, err
+	}
+
+	for _, color := range colors {
+		stats.Colors = append(stats.Colors, *color)
+	}
+
+	slices.SortFunc(stats.Colors, func(a, b CacheColorStats) int { return b.LastUsed.Compare(a.LastUsed) })
+	return stats, nil
+}
+
+// ClearCache removes every rendered file, running timers render theirs again
+func ClearCache() error {
+	FlushCache()
+
+	entries, err := os.ReadDir(CacheDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, entry := range entries {
+		if err = os.RemoveAll(filepath.Join(CacheDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	cache.reset()
+	return nil
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupCache(t *testing.T) {
+	t.Helper()
+	CacheDir = t.TempDir()
+	cache = newFileCache()
+	cacheBytes.Store(0)
+	Overrides.Color = "#3584E4"
+	Overrides.CacheSize = 0
+	t.Cleanup(FlushCache)
+}
+
+// cacheFile creates a file of the given size in the color directory and adds it to the cache
+func cacheFile(t *testing.T, color string, name string, size int, used time.Time) string {
+	t.Helper()
+	dir := filepath.Join(CacheDir, color)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(filename, time.Time{}, used); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cache.adopt(filename) {
+		t.Fatalf("%s was not cached", filename)
+	}
+
+	return filename
+}
+
+// checkCache verifies every cached file is on disk and the total size adds up
+func checkCache(t *testing.T) {
+	t.Helper()
+	var total int64
+	for _, file := range cache.files() {
+		if !fileExists(file.filename) {
+			t.Errorf("%s is cached but missing", file.filename)
+		}
+		total += file.size
+	}
+
+	if total != cacheBytes.Load() {
+		t.Errorf("cache size is %d, expected %d", cacheBytes.Load(), total)
+	}
+}
+
+func TestCacheConcurrentAccess(t *testing.T) {
+	setupCache(t)
+
+	// old files close to the limit, so the new ones push them out
+	for i := range 10 {
+		cacheFile(t, filepath.Base(colorCacheDir()), fmt.Sprintf("old%d.svg", i), 95<<10, time.Now().Add(-time.Hour))
+	}
+	Overrides.CacheSize = 1
+
+	wg := sync.WaitGroup{}
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				if _, err := MakeProgressCircle(Frame{Progress: float64((worker*50 + i) % 300)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		InitCache()
+	}()
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			EvictCache()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			if _, err := ReadCacheStats(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	wg.Wait()
+	FlushCache()
+	checkCache(t)
+}
+
+func TestDigitsFilesPerSecond(t *testing.T) {
+	// a 10 minute timer at 30 fps
+	duration := 10 * time.Minute
+	footprints := map[string]bool{}
+	for i := range int(duration.Seconds() * 30) {
+		elapsed := time.Duration(i) * time.Second / 30
+		frame := Frame{Progress: 100 * elapsed.Seconds() / duration.Seconds(), Left: duration - elapsed, Duration: duration}
+		footprints[frameFootprint(StyleDigits, frame)] = true
+	}
+
+	// a file for every second, and the ring moves by a percent in between
+	if expected := int(duration.Seconds()) + 101; len(footprints) > expected {
+		t.Errorf("%d files, expected %d at most", len(footprints), expected)
+	}
+}
+
+func TestEvictCacheLeastRecentlyUsed(t *testing.T) {
+	setupCache(t)
+
+	color := filepath.Base(colorCacheDir())
+	start := time.Now().Add(-time.Hour)
+	var files []string
+	for i := range 20 {
+		files = append(files, cacheFile(t, color, fmt.Sprintf("%d.svg", i), 100<<10, start.Add(time.Duration(i)*time.Minute)))
+	}
+
+	// the oldest file is used again
+	cache.mu.RLock()
+	cache.entries[files[0]].used.Store(time.Now().UnixNano())
+	cache.mu.RUnlock()
+
+	Overrides.CacheSize = 1
+	EvictCache()
+	checkCache(t)
+
+	if limit := int64(float64(cacheLimit()) * cacheLowWater); cacheBytes.Load() > limit {
+		t.Errorf("cache size is %d, expected at most %d", cacheBytes.Load(), limit)
+	}
+
+	if !fileExists(files[0]) || !fileExists(files[19]) {
+		t.Errorf("recently used files were evicted")
+	}
+
+	if fileExists(files[1]) {
+		t.Errorf("least recently used file was kept")
+	}
+}
+
+func TestEvictCacheStaleColors(t *testing.T) {
+	setupCache(t)
+
+	current := cacheFile(t, filepath.Base(colorCacheDir()), "current.svg", 10, time.Now().Add(-10*24*time.Hour))
+	stale := cacheFile(t, "FF0000", "stale.svg", 10, time.Now().Add(-10*24*time.Hour))
+	recent := cacheFile(t, "00FF00", "recent.svg", 10, time.Now().Add(-time.Hour))
+	expired := cacheFile(t, "00FF00", "expired.svg", 10, time.Now().Add(-40*24*time.Hour))
+
+	EvictCache()
+	checkCache(t)
+
+	for filename, kept := range map[string]bool{current: true, stale: false, recent: true, expired: false} {
+		if fileExists(filename) != kept {
+			t.Errorf("%s: expected kept = %v", filepath.Base(filename), kept)
+		}
+	}
+
+	if fileExists(filepath.Join(CacheDir, "FF0000")) {
+		t.Errorf("directory of the stale color was kept")
+	}
+}
+
+func TestClearCache(t *testing.T) {
+	setupCache(t)
+
+	filename, err := MakeProgressCircle(Frame{Progress: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.setLoaded()
+	if err = ClearCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fileExists(filename) || cache.has(filename) || cacheBytes.Load() != 0 {
+		t.Errorf("cache was not cleared")
+	}
+
+	stats, err := ReadCacheStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Files != 0 {
+		t.Errorf("expected no files, got %d", stats.Files)
+	}
+}
@@ -1,38 +1,420 @@
 package core
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 var Overrides = struct {
-	Notify        bool
-	Sound         bool
-	Volume        float64
-	UseUI         bool
-	Duration      int
-	Title         string
-	Text          string
-	Color         string
-	HasShadow     bool
-	Rounded       bool
-	LowFPS        bool
-	ForceTrayIcon bool
-	SoundFilename string
+	Notify          bool
+	Sound           bool
+	Volume          float64
+	UseUI           bool
+	Daemon          bool
+	Stopwatch       bool
+	Duration        int
+	AlarmAt         time.Time
+	Sequence        []Segment
+	Title           string
+	Text            string
+	Color           string
+	HasShadow       bool
+	Rounded         bool
+	Style           string
+	LowFPS          bool
+	ForceTrayIcon   bool
+	CountSuspend    bool
+	RestoreId       string
+	Snooze          time.Duration
+	Warnings        []Warning
+	Tick            bool
+	Chime           bool
+	Countdown       int
+	TickVolume      float64
+	ChimeVolume     float64
+	CountdownVolume float64
+	Ring            bool
+	Crescendo       bool
+	MaxRing         time.Duration
+	SoundFilename   string
+	SoundName       string
+	AudioSink       string
+	Fade            time.Duration
+	FadeFrom        float64
+	CacheSize       uint
 }{}
 
+// processFlags apply to every timer of the process, they can't differ between the timers of a daemon
+var processFlags = []string{
+	"color", "shadow", "rounded", "style", "lowfps", "tray", "volume", "soundfile", "sound-name", "sink",
+	"fade", "fade-from", "tick-volume", "chime-volume", "countdown-volume", "ring", "crescendo", "max-ring", "snooze", "count-suspend", "cache-size", "cache-dir",
+}
+
+var subcommands = []string{"list", "status", "pause", "resume", "cancel", "add", "lap", "silence", "cache"}
+
 func LoadFlags() {
 	flag.BoolVar(&Overrides.Notify, "notify", UserPrefs.ShouldNotify, "Send desktop notification")
 	flag.BoolVar(&Overrides.Sound, "sound", UserPrefs.EnableSound, "Play sound")
-	flag.StringVar(&Overrides.SoundFilename, "soundfile", UserPrefs.SoundFilename, "Filename of the custom sound (must be .mp3)")
+	flag.StringVar(&Overrides.SoundFilename, "soundfile", UserPrefs.SoundFilename, "Filename of the custom sound (MP3, Ogg Vorbis, FLAC or WAV)")
+	flag.StringVar(&Overrides.SoundName, "sound-name", UserPrefs.SoundName, "Sound of the desktop's sound theme, e.g. complete or alarm-clock-elapsed")
+	Overrides.Fade = time.Duration(UserPrefs.FadeSeconds) * time.Second
+	flag.Func("fade", "Fade the sound in for this long, e.g. 10s (default from preferences)", func(value string) error {
+		// 0 turns the fade off
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		Overrides.Fade = d
+		return nil
+	})
+	flag.Float64Var(&Overrides.FadeFrom, "fade-from", UserPrefs.FadeFrom, "Volume to start fading in from, up to -volume [0-1]")
+	flag.StringVar(&Overrides.AudioSink, "sink", UserPrefs.AudioSink, "PulseAudio/PipeWire sink to play the sounds on, see \"pactl list short sinks\"")
 	flag.Float64Var(&Overrides.Volume, "volume", UserPrefs.Volume, "Volume [0-1]")
 	flag.BoolVar(&Overrides.UseUI, "ui", false, "Show timepicker UI (default true)")
+	flag.BoolVar(&Overrides.Daemon, "daemon", false, "Run in background and host the timers started later")
 	flag.BoolVar(&Overrides.HasShadow, "shadow", UserPrefs.Shadow, "Shadow for progress image")
 	flag.BoolVar(&Overrides.Rounded, "rounded", UserPrefs.Rounded, "Rounded corners")
+	Overrides.Style = UserPrefs.Style
+	flag.Func("style", "Progress image style: "+strings.Join(Styles, ", ")+" (default from preferences)", func(value string) error {
+		style, err := ParseStyle(value)
+		if err != nil {
+			return err
+		}
+
+		Overrides.Style = style
+		return nil
+	})
 	flag.BoolVar(&Overrides.LowFPS, "lowfps", UserPrefs.LowFPS, "1 fps mode (energy saver, GNOME only)")
-	flag.IntVar(&Overrides.Duration, "start", 0, "Start the timer immediately, don't show UI (value in seconds)")
+	flag.Func("start", "Start the timer immediately, don't show UI (90, 25m, 1h30m, 1:30:00)", func(value string) error {
+		d, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		Overrides.Duration = int(d.Round(time.Second) / time.Second)
+		return nil
+	})
+	flag.Func("at", "Start an alarm for the given local time, don't show UI ([YYYY-MM-DD ]HH:MM[:SS])", func(value string) error {
+		at, err := ParseAlarm(value, time.Now())
+		if err != nil {
+			return err
+		}
+
+		Overrides.AlarmAt = at
+		return nil
+	})
+	flag.Func("sequence", "Start a sequence of timers, don't show UI (\"25m work, 5m break ×4, 15m long break\")", func(value string) error {
+		segments, err := ParseSequence(value)
+		if err != nil {
+			return err
+		}
+
+		Overrides.Sequence = segments
+		return nil
+	})
+	flag.BoolVar(&Overrides.Stopwatch, "stopwatch", false, "Start a stopwatch counting up, don't show UI")
 	flag.StringVar(&Overrides.Title, "title", UserPrefs.DefaultTitle, "Name/title of the timer")
 	flag.StringVar(&Overrides.Text, "text", UserPrefs.DefaultText, "Notification text")
 	flag.StringVar(&Overrides.Color, "color", UserPrefs.ProgressColor, "Progress color (#HEX) for the player, use \"default\" for the GTK accent color")
 	flag.BoolVar(&Overrides.ForceTrayIcon, "tray", UserPrefs.ForceTrayIcon, "Force tray icon presence")
+	flag.BoolVar(&Overrides.CountSuspend, "count-suspend", UserPrefs.CountSuspend, "Count the time the system spent in suspend (alarms always do)")
+	Overrides.Snooze = time.Duration(max(UserPrefs.SnoozeMinutes, 1)) * time.Minute
+	flag.Func("snooze", "Snooze length offered by the finish notification, e.g. 5m (default from preferences)", func(value string) error {
+		d, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		if d < time.Second {
+			return fmt.Errorf("snooze must be at least 1 second")
+		}
+
+		Overrides.Snooze = d.Round(time.Second)
+		return nil
+	})
+	Overrides.Warnings, _ = ParseWarnings(UserPrefs.Warnings)
+	flag.Func("warn", "Warn before the end, e.g. \"5m, 1m sound, 90% notify\" (default from preferences)", func(value string) error {
+		warnings, err := ParseWarnings(value)
+		if err != nil {
+			return err
+		}
+
+		Overrides.Warnings = warnings
+		return nil
+	})
+	flag.BoolVar(&Overrides.Tick, "tick", UserPrefs.Tick, "Tick every second while running")
+	flag.BoolVar(&Overrides.Chime, "chime", UserPrefs.Chime, "Chime every minute while running")
+	flag.IntVar(&Overrides.Countdown, "countdown", int(UserPrefs.Countdown), "Count down the last seconds, with beeps or the samples of DataDir/countdown [0-10]")
+	flag.Float64Var(&Overrides.TickVolume, "tick-volume", UserPrefs.TickVolume, "Volume of the tick, relative to -volume [0-1]")
+	flag.Float64Var(&Overrides.ChimeVolume, "chime-volume", UserPrefs.ChimeVolume, "Volume of the chime, relative to -volume [0-1]")
+	flag.Float64Var(&Overrides.CountdownVolume, "countdown-volume", UserPrefs.CountdownVolume, "Volume of the countdown, relative to -volume [0-1]")
+	flag.BoolVar(&Overrides.Ring, "ring", UserPrefs.RingUntilDismissed, "Repeat the sound until the timer is dismissed")
+	flag.BoolVar(&Overrides.Crescendo, "crescendo", UserPrefs.Crescendo, "Start ringing quietly and get louder")
+	Overrides.MaxRing = time.Duration(max(UserPrefs.MaxRingMinutes, 1)) * time.Minute
+	flag.Func("max-ring", "Stop ringing after this long anyway, e.g. 5m (default from preferences)", func(value string) error {
+		d, err := ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		if d < time.Second {
+			return fmt.Errorf("max ring must be at least 1 second")
+		}
+
+		Overrides.MaxRing = d
+		return nil
+	})
+	flag.UintVar(&Overrides.CacheSize, "cache-size", UserPrefs.CacheSizeMB, "Size limit of the image cache in MB, 0 for no limit")
+	flag.Func("cache-dir", "Directory of the image cache, e.g. on tmpfs (default $XDG_CACHE_HOME/"+AppId+")", setCacheDir)
+	flag.StringVar(&Overrides.RestoreId, "restore", "", "Restore the saved timer with the given id, don't show UI")
 	flag.Parse()
+
+	if !Overrides.AlarmAt.IsZero() {
+		if Overrides.Duration > 0 {
+			log.Fatalf("-at can't be used with -start")
+		}
+
+		Overrides.Duration = SecondsUntil(Overrides.AlarmAt)
+	}
+
+	if len(Overrides.Sequence) > 0 {
+		if Overrides.Duration > 0 {
+			log.Fatalf("-sequence can't be used with -start or -at")
+		}
+
+		Overrides.Duration = int(TotalDuration(Overrides.Sequence) / time.Second)
+	}
+
+	Overrides.Countdown = min(max(Overrides.Countdown, 0), maxCountdown)
+	Overrides.TickVolume = min(max(Overrides.TickVolume, 0), 1)
+	Overrides.ChimeVolume = min(max(Overrides.ChimeVolume, 0), 1)
+	Overrides.CountdownVolume = min(max(Overrides.CountdownVolume, 0), 1)
+	Overrides.FadeFrom = min(max(Overrides.FadeFrom, 0), 1)
+
+	if Overrides.Stopwatch && Overrides.Duration > 0 {
+		log.Fatalf("-stopwatch can't be used with -start, -at or -sequence")
+	}
+}
+
+// ProcessFlags are the processFlags given on the command line
+func ProcessFlags() []string {
+	var flags []string
+	flag.Visit(func(f *flag.Flag) {
+		if slices.Contains(processFlags, f.Name) {
+			flags = append(flags, f.Name)
+		}
+	})
+
+	return flags
+}
+
+func IsSubcommand(arg string) bool {
+	return slices.Contains(subcommands, arg)
+}
+
+// RunSubcommand controls the running timers, returns the exit code
+func RunSubcommand(args []string) int {
+	asJSON := slices.Contains(args, "--json") || slices.Contains(args, "-json")
+	args = slices.DeleteFunc(args, func(arg string) bool { return arg == "--json" || arg == "-json" })
+
+	var err error
+	switch cmd := args[0]; cmd {
+	case "list":
+		err = listCommand(asJSON)
+	case "status":
+		query := ""
+		if len(args) > 1 {
+			query = args[1]
+		}
+		err = statusCommand(query, asJSON)
+	case "pause", "resume", "cancel", "lap":
+		if len(args) != 2 {
+			err = fmt.Errorf("usage: play-timer %s <id|title>", cmd)
+			break
+		}
+		err = controlCommand(args[1], strings.ToUpper(cmd[:1])+cmd[1:])
+	case "add":
+		if len(args) != 3 {
+			err = fmt.Errorf("usage: play-timer add <id|title> <+5m|-30s>")
+			break
+		}
+		err = addCommand(args[1], args[2])
+	case "silence":
+		err = SilenceTimers()
+	case "cache":
+		flags := flag.NewFlagSet("cache", flag.ContinueOnError)
+		flags.Func("cache-dir", "Directory of the image cache", setCacheDir)
+		if len(args) < 2 || (args[1] != "stats" && args[1] != "clear") || flags.Parse(args[2:]) != nil {
+			err = fmt.Errorf("usage: play-timer cache <stats|clear> [-cache-dir dir]")
+			break
+		}
+		err = cacheCommand(args[1], asJSON)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func listCommand(asJSON bool) error {
+	timers, err := ListTimers()
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(timerInfos(timers))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tSTATUS\tLEFT\tTITLE")
+	for _, timer := range timers {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", timer.Id, timer.Status, formatSeconds(timer.Remaining), timer.Title)
+	}
+
+	return w.Flush()
+}
+
+func statusCommand(query string, asJSON bool) error {
+	var timers []RemoteTimer
+	if query == "" {
+		var err error
+		if timers, err = ListTimers(); err != nil {
+			return err
+		}
+	} else {
+		timer, err := FindTimer(query)
+		if err != nil {
+			return err
+		}
+		timers = append(timers, timer)
+	}
+
+	if asJSON {
+		if query != "" {
+			return printJSON(timers[0].TimerInfo)
+		}
+		return printJSON(timerInfos(timers))
+	}
+
+	for _, timer := range timers {
+		fmt.Printf("%s %s: %s of %s left, %s\n", timer.Id, timer.Title,
+			formatSeconds(timer.Remaining), formatSeconds(timer.Duration), strings.ToLower(timer.Status))
+	}
+
+	return nil
+}
+
+func controlCommand(query string, method string) error {
+	timer, err := FindTimer(query)
+	if err != nil {
+		return err
+	}
+
+	return timer.Call(method)
+}
+
+func addCommand(query string, value string) error {
+	d, err := ParseDuration(strings.TrimLeft(value, "+-"))
+	if err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(value, "-") {
+		d = -d
+	}
+
+	timer, err := FindTimer(query)
+	if err != nil {
+		return err
+	}
+
+	return timer.Call("AddTime", int32(d/time.Second))
+}
+
+func setCacheDir(value string) error {
+	dir, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	CacheDir = dir
+	return nil
+}
+
+func cacheCommand(cmd string, asJSON bool) error {
+	if cmd == "clear" {
+		return ClearCache()
+	}
+
+	LoadPrefs()
+	Overrides.CacheSize = UserPrefs.CacheSizeMB
+	stats, err := ReadCacheStats()
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(stats)
+	}
+
+	limit := "no limit"
+	if stats.Limit > 0 {
+		limit = formatBytes(stats.Limit)
+	}
+
+	fmt.Printf("%s: %d files, %s (limit: %s)\n", stats.Dir, stats.Files, formatBytes(stats.Bytes), limit)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "COLOR\tFILES\tSIZE\tLAST USED")
+	for _, color := range stats.Colors {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", color.Color, color.Files, formatBytes(color.Bytes), color.LastUsed.Format(time.DateTime))
+	}
+
+	return w.Flush()
+}
+
+func timerInfos(timers []RemoteTimer) []TimerInfo {
+	infos := make([]TimerInfo, 0, len(timers))
+	for _, timer := range timers {
+		infos = append(infos, timer.TimerInfo)
+	}
+
+	return infos
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatSeconds(seconds int64) string {
+	return FormatDuration(time.Duration(seconds) * time.Second)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+
+	return fmt.Sprintf("%d B", n)
 }
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"slices"
+	"strings"
+)
+
+// RemoteTimer is a timer running in another process
+type RemoteTimer struct {
+	TimerInfo
+	busName string
+}
+
+// ListTimers asks every instance on the session bus, the daemon included, for its timers
+func ListTimers() ([]RemoteTimer, error) {
+	conn, names, err := instances()
+	if err != nil {
+		return nil, err
+	}
+
+	var timers []RemoteTimer
+	for _, name := range names {
+		// timers hosted by the daemon have no control interface of their own
+		var infos []TimerInfo
+		if err = conn.Object(name, ControlPath).Call(ControlIface+".List", 0).Store(&infos); err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			seen := slices.ContainsFunc(timers, func(t RemoteTimer) bool { return t.Id == info.Id })
+			if !seen {
+				timers = append(timers, RemoteTimer{TimerInfo: info, busName: name})
+			}
+		}
+	}
+
+	slices.SortFunc(timers, func(a, b RemoteTimer) int { return strings.Compare(a.Id, b.Id) })
+	return timers, nil
+}
+
+// FindTimer looks the timer up by id, then by title
+func FindTimer(query string) (RemoteTimer, error) {
+	timers, err := ListTimers()
+	if err != nil {
+		return RemoteTimer{}, err
+	}
+
+	for _, timer := range timers {
+		if timer.Id == query {
+			return timer, nil
+		}
+	}
+
+	var found []RemoteTimer
+	for _, timer := range timers {
+		if strings.EqualFold(timer.Title, query) {
+			found = append(found, timer)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return RemoteTimer{}, fmt.Errorf("no such timer: %s", query)
+	case 1:
+		return found[0], nil
+	default:
+		return RemoteTimer{}, fmt.Errorf("%d timers are titled %q, use the id", len(found), query)
+	}
+}
+
+// Call invokes a method of the control interface for this timer
+func (t RemoteTimer) Call(method string, args ...any) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return fmt.Errorf("connect to session bus: %w", err)
+	}
+
+	args = append([]any{t.Id}, args...)
+	return conn.Object(t.busName, ControlPath).Call(ControlIface+"."+method, 0, args...).Err
+}
+
+// SilenceTimers stops the ringing in every instance on the session bus
+func SilenceTimers() error {
+	conn, names, err := instances()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		// timers hosted by the daemon have no control interface of their own
+		_ = conn.Object(name, ControlPath).Call(ControlIface+".Silence", 0).Err
+	}
+
+	return nil
+}
+
+// instances are the bus names of the daemon and the standalone timers
+func instances() (*dbus.Conn, []string, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to session bus: %w", err)
+	}
+
+	var names []string
+	err = conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list names: %w", err)
+	}
+
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return name != DaemonName && !strings.HasPrefix(name, DaemonName+".")
+	})
+
+	return conn, names, nil
+}
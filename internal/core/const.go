@@ -38,13 +38,49 @@ const svgTemplate = `
 	/>
 </svg>`
 
+const pieTemplate = `
+<svg width="{{.Width}}" height="{{.Height}}">
+  <style>{{if .HasShadow}}#progress{filter: drop-shadow(-4px 7px 6px rgb(16 16 16 / 0.2));}{{end}}</style>
+  <circle cx="{{.CenterX}}" cy="{{.CenterY}}" r="{{.Radius}}" fill="{{.BgStrokeColor}}" />
+  {{if .Path}}<path id="progress" d="{{.Path}}" fill="{{.FgStrokeColor}}" />{{end}}
+</svg>`
+
+const barTemplate = `
+<svg width="{{.Width}}" height="{{.Height}}">
+  <style>{{if .HasShadow}}#progress{filter: drop-shadow(-4px 7px 6px rgb(16 16 16 / 0.2));}{{end}}</style>
+  <rect x="{{.BarX}}" y="{{.BarY}}" width="{{.BarLength}}" height="{{.StrokeWidth}}" rx="{{.Corners}}" ry="{{.Corners}}" fill="{{.BgStrokeColor}}" />
+  {{if .BarFill}}<rect id="progress" x="{{.BarX}}" y="{{.BarY}}" width="{{.BarFill}}" height="{{.StrokeWidth}}" rx="{{.FillCorners}}" ry="{{.FillCorners}}" fill="{{.FgStrokeColor}}" />{{end}}
+</svg>`
+
+const segmentsTemplate = `
+<svg width="{{.Width}}" height="{{.Height}}">
+  <style>{{if .HasShadow}}#progress{filter: drop-shadow(-4px 7px 6px rgb(16 16 16 / 0.2));}{{end}}</style>
+  <path d="{{.Path}}" fill="none" stroke="{{.BgStrokeColor}}" stroke-width="{{.StrokeWidth}}" />
+  {{if .DonePath}}<path id="progress" d="{{.DonePath}}" fill="none" stroke="{{.FgStrokeColor}}" stroke-width="{{.StrokeWidth}}" />{{end}}
+</svg>`
+
+// digitsTemplate has the digits as the outlines of Go Mono Bold, the font RenderProgress draws them with,
+// so they look the same whatever fonts the SVG renderer has
+const digitsTemplate = `
+<svg width="{{.Width}}" height="{{.Height}}">
+  <style>{{if .HasShadow}}#progress{filter: drop-shadow(-4px 7px 6px rgb(16 16 16 / 0.2));}{{end}}</style>
+  <circle cx="{{.CenterX}}" cy="{{.CenterY}}" r="{{.Radius}}" fill="none" stroke="{{.BgStrokeColor}}" stroke-width="{{.BaseWidth}}" />
+  <circle id="progress"
+		cx="{{.CenterX}}" cy="{{.CenterY}}" r="{{.Radius}}" fill="none" stroke="{{.FgStrokeColor}}"
+		stroke-width="{{.BaseWidth}}" stroke-dasharray="{{.Circumference}}" stroke-dashoffset="{{.DashOffset}}"
+		transform="rotate(-90 {{.CenterX}} {{.CenterY}})"
+	/>
+  <path d="{{.TextPath}}" fill="{{.FgStrokeColor}}" />
+</svg>`
+
 var (
 	IsPlasma    bool
 	IsGnome     bool
 	BreezeTheme bool
 	CacheDir    string
 	DataDir     string
-	svgTpl      *template.Template
+	StateDir    string
+	svgTpls     = map[string]*template.Template{}
 )
 
 type svgParams struct {
@@ -63,25 +99,63 @@ type svgParams struct {
 	Rounded       bool
 	CustomOrigin  int
 	Progress      int
+	Style         string
+	Path          string // the pie slice, or every segment
+	DonePath      string // the segments done
+	BarX          float64
+	BarY          float64
+	BarLength     float64
+	BarFill       float64
+	Corners       float64
+	FillCorners   float64
+	Text          string // the digits
+	TextPath      string // the digits as glyph outlines
+	FontSize      int
+	// for the custom templates, the names are XML escaped
+	Remaining        string
+	RemainingSeconds int
+	Title            string
+	Phase            string
+	Step             int
+	Steps            int
 }
 
 func init() {
-	var err error
-	svgTpl, err = tpl.Parse(svgTemplate)
-	if err != nil {
-		log.Println(err)
-	}
+	for style, text := range map[string]string{
+		StyleRing:     svgTemplate,
+		StylePie:      pieTemplate,
+		StyleBar:      barTemplate,
+		StyleSegments: segmentsTemplate,
+		StyleDigits:   digitsTemplate,
+	} {
+		t, err := template.New(style).Parse(text)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
 
-	DataDir = glib.GetUserDataDir()
-	if !strings.Contains(DataDir, AppId) {
-		DataDir = path.Join(DataDir, AppId)
+		svgTpls[style] = t
 	}
 
-	CacheDir, _ = os.UserHomeDir()
-	CacheDir = path.Join(CacheDir, ".var", "app", AppId, "cache")
+	DataDir = appDir(glib.GetUserDataDir())
+	CacheDir = appDir(glib.GetUserCacheDir())
+	StateDir = appDir(glib.GetUserStateDir())
 
 	_ = os.MkdirAll(CacheDir, 0755)
 	_ = os.MkdirAll(DataDir, 0755)
+	_ = os.MkdirAll(StateDir, 0755)
+
+	loadTemplates()
+}
+
+// appDir is the app's own subdirectory of an XDG base directory,
+// flatpak already gives every app its own, e.g. ~/.var/app/<AppId>/cache
+func appDir(base string) string {
+	if strings.Contains(base, AppId) {
+		return base
+	}
+
+	return path.Join(base, AppId)
 }
 
 func CalculateFps() int {
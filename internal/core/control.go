@@ -0,0 +1,278 @@
+package core
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	ControlPath  = dbus.ObjectPath("/io/github/efogdev/PlayTimer1")
+	ControlIface = "io.github.efogdev.PlayTimer1"
+	errNotFound  = ControlIface + ".Error.NotFound"
+)
+
+// durations are in seconds, Status is the MPRIS PlaybackStatus
+const controlIntrospection = `
+<node>
+  <interface name="` + ControlIface + `">
+    <method name="Create">
+      <arg name="duration" type="u" direction="in"/>
+      <arg name="title" type="s" direction="in"/>
+      <arg name="options" type="a{sv}" direction="in"/>
+      <arg name="id" type="s" direction="out"/>
+    </method>
+    <method name="List">
+      <arg name="timers" type="a(sssxx)" direction="out"/>
+    </method>
+    <method name="Pause">
+      <arg name="id" type="s" direction="in"/>
+    </method>
+    <method name="Resume">
+      <arg name="id" type="s" direction="in"/>
+    </method>
+    <method name="AddTime">
+      <arg name="id" type="s" direction="in"/>
+      <arg name="seconds" type="i" direction="in"/>
+    </method>
+    <method name="Lap">
+      <arg name="id" type="s" direction="in"/>
+    </method>
+    <method name="Silence"/>
+    <method name="Cancel">
+      <arg name="id" type="s" direction="in"/>
+    </method>
+    <signal name="Started">
+      <arg name="id" type="s"/>
+      <arg name="title" type="s"/>
+      <arg name="duration" type="x"/>
+    </signal>
+    <signal name="PhaseStarted">
+      <arg name="id" type="s"/>
+      <arg name="name" type="s"/>
+      <arg name="index" type="u"/>
+    </signal>
+    <signal name="Warning">
+      <arg name="id" type="s"/>
+      <arg name="remaining" type="x"/>
+    </signal>
+    <signal name="Finished">
+      <arg name="id" type="s"/>
+      <arg name="cancelled" type="b"/>
+    </signal>
+    <signal name="Tick">
+      <arg name="id" type="s"/>
+      <arg name="remaining" type="x"/>
+    </signal>
+  </interface>` + introspect.IntrospectDataString + `</node>`
+
+// TimerInfo is a single entry of List, the order of fields is the D-Bus signature
+type TimerInfo struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	Duration  int64  `json:"duration"`
+	Remaining int64  `json:"remaining"`
+}
+
+// Control implements io.github.efogdev.PlayTimer1 on top of the timers it hosts.
+// Signals are sent by the timers themselves, so they come from the timer's bus name.
+type Control struct {
+	OnFinish   func(timer *TimerPlayer)
+	OnPhaseEnd func(timer *TimerPlayer, ended Segment)
+	OnWarning  func(timer *TimerPlayer, warning Warning, left time.Duration)
+	conn       *dbus.Conn
+	mu         sync.Mutex
+	timers     map[string]*TimerPlayer
+	canCreate  bool
+}
+
+func newControl(canCreate bool) *Control {
+	return &Control{
+		timers:    make(map[string]*TimerPlayer),
+		canCreate: canCreate,
+	}
+}
+
+// ExportControl serves the control interface of a standalone timer on its own bus name
+func ExportControl(timer *TimerPlayer) error {
+	c := newControl(false)
+	c.conn = timer.conn
+	c.track(timer)
+	return c.export()
+}
+
+func (c *Control) export() error {
+	if err := c.conn.Export(c, ControlPath, ControlIface); err != nil {
+		return err
+	}
+
+	return c.conn.Export(introspect.Introspectable(controlIntrospection), ControlPath, "org.freedesktop.DBus.Introspectable")
+}
+
+func (c *Control) AddTimer(timer *TimerPlayer) error {
+	c.mu.Lock()
+	_, exists := c.timers[timer.Id]
+	c.mu.Unlock()
+	if exists {
+		return fmt.Errorf("timer %s is already running", timer.Id)
+	}
+
+	if timer.OnPhaseEnd == nil {
+		timer.OnPhaseEnd = c.OnPhaseEnd
+	}
+
+	if timer.OnWarning == nil {
+		timer.OnWarning = c.OnWarning
+	}
+
+	if err := timer.Start(); err != nil {
+		return err
+	}
+
+	c.track(timer)
+	return nil
+}
+
+func (c *Control) track(timer *TimerPlayer) {
+	c.mu.Lock()
+	c.timers[timer.Id] = timer
+	running := len(c.timers)
+	c.mu.Unlock()
+
+	log.Printf("timer %s started, %d running", timer.Id, running)
+	go func() {
+		<-timer.Done
+
+		c.mu.Lock()
+		delete(c.timers, timer.Id)
+		c.mu.Unlock()
+
+		if !timer.IsCancelled && c.OnFinish != nil {
+			c.OnFinish(timer)
+		}
+	}()
+}
+
+func (c *Control) timer(id string) (*TimerPlayer, *dbus.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer, ok := c.timers[id]
+	if !ok {
+		return nil, dbus.NewError(errNotFound, []any{fmt.Sprintf("no such timer: %s", id)})
+	}
+
+	return timer, nil
+}
+
+func (c *Control) Create(duration uint32, title string, options map[string]dbus.Variant) (string, *dbus.Error) {
+	if !c.canCreate {
+		return "", dbus.MakeFailedError(fmt.Errorf("not a daemon, run play-timer -daemon"))
+	}
+
+	opts := DefaultTimerOptions()
+	opts.AlarmAt = time.Time{}
+	opts.Sequence = nil
+	opts.Stopwatch = false
+	opts.apply(options)
+
+	var timer *TimerPlayer
+	var err error
+	switch {
+	case opts.Stopwatch:
+		timer, err = NewStopwatchPlayer(title)
+	case len(opts.Sequence) > 0:
+		timer, err = NewSequencePlayer(opts.Sequence, title)
+	case !opts.AlarmAt.IsZero():
+		timer, err = NewAlarmPlayer(opts.AlarmAt, title)
+	default:
+		timer, err = NewTimerPlayer(int(duration), title)
+	}
+
+	if err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+
+	timer.Options = opts
+	if err = c.AddTimer(timer); err != nil {
+		return "", dbus.MakeFailedError(err)
+	}
+
+	return timer.Id, nil
+}
+
+func (c *Control) List() ([]TimerInfo, *dbus.Error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	list := make([]TimerInfo, 0, len(c.timers))
+	for _, timer := range c.timers {
+		list = append(list, timer.Info())
+	}
+
+	return list, nil
+}
+
+func (c *Control) Pause(id string) *dbus.Error {
+	timer, err := c.timer(id)
+	if err != nil {
+		return err
+	}
+
+	return timer.Pause()
+}
+
+func (c *Control) Resume(id string) *dbus.Error {
+	timer, err := c.timer(id)
+	if err != nil {
+		return err
+	}
+
+	return timer.Play()
+}
+
+func (c *Control) AddTime(id string, seconds int32) *dbus.Error {
+	timer, err := c.timer(id)
+	if err != nil {
+		return err
+	}
+
+	if err := timer.AddTime(time.Duration(seconds) * time.Second); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+
+	return nil
+}
+
+func (c *Control) Lap(id string) *dbus.Error {
+	timer, err := c.timer(id)
+	if err != nil {
+		return err
+	}
+
+	if err := timer.Lap(); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+
+	return nil
+}
+
+// Silence stops all the timers of the process that are ringing, see Ring
+func (c *Control) Silence() *dbus.Error {
+	SilenceAll()
+	return nil
+}
+
+func (c *Control) Cancel(id string) *dbus.Error {
+	timer, err := c.timer(id)
+	if err != nil {
+		return err
+	}
+
+	timer.Cancel()
+	return nil
+}
@@ -3,10 +3,9 @@ package core
 import (
 	"fmt"
 	"github.com/godbus/dbus/v5"
+	"github.com/google/uuid"
 	"log"
 	"math"
-	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,32 +14,71 @@ import (
 const (
 	baseInterval      = time.Millisecond * 5
 	lowPresicionAfter = time.Second * 300
+	trackId           = dbus.ObjectPath("/track/1")
 )
 
 type PropsChangedEvent struct {
 	Text     string
 	Img      string
+	Frame    Frame
 	IsPaused bool
 }
 
+// TimerOptions are the per-timer settings, also passed to the daemon along with Create
+type TimerOptions struct {
+	Text      string
+	Notify    bool
+	Sound     bool
+	AlarmAt   time.Time
+	Sequence  []Segment
+	Stopwatch bool
+	Warnings  []Warning
+	Tick      bool
+	Chime     bool
+	Countdown int
+}
+
 type TimerPlayer struct {
+	Id             string
 	Name           string
+	Options        TimerOptions
 	Done           chan struct{}
+	OnPhaseEnd     func(timer *TimerPlayer, ended Segment)
+	OnWarning      func(timer *TimerPlayer, warning Warning, left time.Duration)
 	IsFinished     bool
+	IsCancelled    bool
+	IsMissed       bool
+	keepState      bool
 	tickerDone     chan struct{}
+	sleep          chan bool
+	destroyOnce    sync.Once
+	silence        chan struct{}
+	silenceOnce    sync.Once
+	released       chan struct{}
 	emitter        chan PropsChangedEvent
 	serviceName    string
 	playbackStatus string
 	progressText   string
 	img            string
 	progress       float64
+	frame          Frame
 	isPaused       bool
+	wallClock      bool
+	lastTick       int64
 	fps            int
+	phase          int
 	duration       time.Duration
 	startTime      time.Time
 	pausedAt       time.Time
+	sleptAt        time.Time
+	restoredAt     time.Duration
 	interval       time.Duration
 	pausedFor      time.Duration
+	laps           []time.Duration
+	warned         []bool
+	warnedMu       sync.Mutex
+	initial        time.Duration
+	initialSteps   []Segment
 	objectPath     dbus.ObjectPath
 	conn           *dbus.Conn
 	subscribers    []func(event PropsChangedEvent)
@@ -60,31 +98,77 @@ func NewTimerPlayer(seconds int, name string) (*TimerPlayer, error) {
 	}
 
 	return &TimerPlayer{
+		Id:             newTimerId(),
 		Name:           name,
+		Options:        DefaultTimerOptions(),
 		duration:       time.Duration(seconds) * time.Second,
 		objectPath:     "/org/mpris/MediaPlayer2",
 		playbackStatus: "Playing",
 		interval:       interval,
 		fps:            fps,
-		tickerDone:     make(chan struct{}),
+		tickerDone:     make(chan struct{}, 1),
+		silence:        make(chan struct{}),
+		released:       make(chan struct{}),
+		sleep:          make(chan bool, 2),
 		emitter:        make(chan PropsChangedEvent, 1),
 		Done:           make(chan struct{}, 1),
 	}, nil
 }
 
+// newTimerId is random, the timers of a daemon and of separate processes must not collide
+func newTimerId() string {
+	return uuid.NewString()[:8]
+}
+
+// NewAlarmPlayer creates a timer that ends at the given wall clock time.
+// An alarm due any moment (e.g. parsed a moment ago) rings in a second.
+func NewAlarmPlayer(at time.Time, name string) (*TimerPlayer, error) {
+	now := time.Now()
+	if at.Before(now.Add(-time.Second)) {
+		return nil, fmt.Errorf("alarm at %s is in the past", at.Format(time.DateTime))
+	}
+
+	if at.Before(now.Add(time.Second)) {
+		at = now.Add(time.Second)
+	}
+
+	p, err := NewTimerPlayer(SecondsUntil(at), name)
+	if err != nil {
+		return nil, fmt.Errorf("alarm at %s: %w", at.Format(time.DateTime), err)
+	}
+
+	p.Options.AlarmAt = at
+	return p, nil
+}
+
+func DefaultTimerOptions() TimerOptions {
+	return TimerOptions{
+		Text:      Overrides.Text,
+		Notify:    Overrides.Notify,
+		Sound:     Overrides.Sound,
+		AlarmAt:   Overrides.AlarmAt,
+		Sequence:  Overrides.Sequence,
+		Stopwatch: Overrides.Stopwatch,
+		Warnings:  Overrides.Warnings,
+		Tick:      Overrides.Tick,
+		Chime:     Overrides.Chime,
+		Countdown: Overrides.Countdown,
+	}
+}
+
 func (p *TimerPlayer) AddSubscription(onProgress func(event PropsChangedEvent)) {
 	p.subscribers = append(p.subscribers, onProgress)
 }
 
 func (p *TimerPlayer) Start() error {
-	id := strconv.Itoa(int(time.Now().UnixMicro()))[8:]
-	conn, err := dbus.SessionBus()
+	// private connection, every timer owns its own MPRIS name
+	conn, err := dbus.ConnectSessionBus()
 	if err != nil {
 		return fmt.Errorf("connect to session bus: %w", err)
 	}
 
 	p.conn = conn
-	p.serviceName = fmt.Sprintf("org.mpris.MediaPlayer2.%s.run-%s", AppId, id)
+	p.serviceName = fmt.Sprintf("%s.run-%s", DaemonName, p.Id)
 
 	reply, err := conn.RequestName(p.serviceName, dbus.NameFlagAllowReplacement)
 	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
@@ -95,30 +179,121 @@ func (p *TimerPlayer) Start() error {
 		return fmt.Errorf("export interfaces: %w", err)
 	}
 
-	p.startTime = time.Now()
+	// decided once, so that changing the preference doesn't mix the clocks of a running timer
+	p.wallClock = p.isAlarm() || Overrides.CountSuspend
+
+	// a restored timer continues from where it was
+	p.startTime = p.now().Add(-p.restoredAt)
+	if p.isPaused {
+		p.pausedAt = p.now()
+	}
+
+	if p.isAlarm() {
+		p.duration = p.Options.AlarmAt.Sub(p.startTime)
+	}
+
+	// remembered for Restart, a rearmed timer keeps the original
+	if p.initial == 0 {
+		p.initial = p.duration
+		p.initialSteps = p.Options.Sequence
+	}
+
+	p.armWarnings()
+	subscribeSleep(p.Id, p.sleep)
+	p.save()
 	go p.runTicker()
 	go p.emitLoop()
 
+	p.emitControl("Started", p.Id, p.Name, int64(p.duration/time.Second))
 	return nil
 }
 
+// Destroy releases the bus name and signals Done, use Cancel to stop a running timer
 func (p *TimerPlayer) Destroy() {
-	defer func() { _ = p.conn.Close() }()
-	close(p.emitter)
+	p.destroyOnce.Do(func() {
+		unsubscribeSleep(p.Id)
+		if !p.keepState {
+			ForgetTimer(p.Id)
+		}
+
+		close(p.emitter)
+		p.emitControl("Finished", p.Id, p.IsCancelled)
+
+		// a ringing timer stays on the bus, so that it can be silenced over MPRIS or D-Bus
+		if p.ringsUntilSilenced() {
+			p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
+				"Metadata": dbus.MakeVariant(p.metadata(p.Options.Text, p.img)),
+			})
+
+			go func() {
+				<-p.silence
+				p.release()
+			}()
+		} else {
+			p.release()
+		}
 
-	if p.Done != nil {
 		p.Done <- struct{}{}
+		close(p.Done)
+	})
+}
+
+func (p *TimerPlayer) release() {
+	p.playbackStatus = "Stopped"
+	p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
+		"PlaybackStatus": dbus.MakeVariant(p.playbackStatus),
+	})
+
+	if _, err := p.conn.ReleaseName(p.serviceName); err != nil {
+		log.Printf("release bus name: %v", err)
 	}
 
-	close(p.Done)
+	_ = p.conn.Close()
+	FlushCache()
+	close(p.released)
+}
+
+func (p *TimerPlayer) ringsUntilSilenced() bool {
+	return p.IsFinished && p.Options.Sound && Overrides.Ring
+}
+
+// Silence stops the ringing of a finished timer, see Ring
+func (p *TimerPlayer) Silence() {
+	p.silenceOnce.Do(func() { close(p.silence) })
+}
+
+func (p *TimerPlayer) Silenced() <-chan struct{} {
+	return p.silence
+}
+
+// Released is closed once the destroyed timer has left the bus, a ringing one after it's silenced
+func (p *TimerPlayer) Released() <-chan struct{} {
+	return p.released
+}
+
+// Detach stops the timer but keeps its saved state, so that it can be restored later
+func (p *TimerPlayer) Detach() {
+	p.keepState = true
+	p.Cancel()
+}
+
+// Cancel stops the timer, the finish actions are not expected to run after that
+func (p *TimerPlayer) Cancel() {
+	p.IsCancelled = true
+
+	select {
+	case p.tickerDone <- struct{}{}:
+	default:
+	}
 }
 
 func (p *TimerPlayer) runTicker() {
 	log.Printf("player requested, fps = %d", p.fps)
 
 	p.progress = 0.0
+	p.frame = p.newFrame(p.duration, p.duration)
 	mu := sync.Mutex{}
-	img, _ := MakeProgressCircle(0)
+	img, _ := MakeProgressCircle(p.frame)
 	img = "file://" + img
 	p.img = img
 
@@ -127,7 +302,7 @@ func (p *TimerPlayer) runTicker() {
 	go func() {
 		for range renderTicker.C {
 			mu.Lock()
-			img, _ = MakeProgressCircle(p.progress)
+			img, _ = MakeProgressCircle(p.frame)
 			img = "file://" + img
 			p.img = img
 			mu.Unlock()
@@ -136,21 +311,55 @@ func (p *TimerPlayer) runTicker() {
 
 	ticker := time.NewTicker(p.interval)
 	defer ticker.Stop()
+	lastTick := p.now()
 	for {
 		select {
 		case <-p.tickerDone:
 			p.Destroy()
 			return
+		case sleeping := <-p.sleep:
+			if sleeping {
+				p.sleptAt = p.now()
+			} else if !p.sleptAt.IsZero() {
+				p.resume()
+			}
 		case <-ticker.C:
-			if p.isPaused {
-				continue
+			// the first tick after resume may come before PrepareForSleep(false)
+			if !p.sleptAt.IsZero() && p.now().Sub(lastTick) > time.Second {
+				p.resume()
 			}
+			lastTick = p.now()
 
-			elapsed := time.Since(p.startTime) - p.pausedFor
+			// elapsed() is frozen while paused, but a seek may still move it
+			elapsed := p.elapsed()
 			timeLeft := p.duration - elapsed
 
 			mu.Lock()
-			p.progress = math.Min(100, (float64(elapsed)/float64(p.duration))*100)
+			if p.isStopwatch() {
+				// counts up, the text shows the elapsed time
+				p.progress = stopwatchProgress(elapsed)
+				timeLeft = elapsed
+				p.frame = p.newFrame(elapsed, stopwatchLoop(elapsed))
+			} else {
+				p.progress = math.Min(100, (float64(elapsed)/float64(p.duration))*100)
+				p.frame = p.newFrame(max(timeLeft, 0), p.duration)
+			}
+
+			if p.progress == 100 && p.hasNextPhase() {
+				ended := p.Phase()
+				p.nextPhase(p.startTime.Add(p.duration + p.pausedFor))
+				mu.Unlock()
+
+				if p.OnPhaseEnd != nil {
+					go p.OnPhaseEnd(p, ended)
+				}
+				continue
+			}
+
+			if !p.isStopwatch() && p.progress < 100 {
+				p.checkWarnings(elapsed)
+			}
+
 			if p.progress == 100 {
 				p.IsFinished = true
 				p.broadcast()
@@ -163,11 +372,18 @@ func (p *TimerPlayer) runTicker() {
 			p.emitter <- PropsChangedEvent{
 				Text:     p.progressText,
 				Img:      img,
+				Frame:    p.frame,
 				IsPaused: p.isPaused,
 			}
 			mu.Unlock()
 
 			p.broadcast()
+
+			if tick := int64(timeLeft.Round(time.Second) / time.Second); tick != p.lastTick {
+				p.lastTick = tick
+				p.emitControl("Tick", p.Id, tick)
+				p.cue(tick)
+			}
 		}
 	}
 }
@@ -182,24 +398,120 @@ func (p *TimerPlayer) emitLoop() {
 
 		p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
 			"PlaybackStatus": dbus.MakeVariant(p.playbackStatus),
-			"Metadata": dbus.MakeVariant(map[string]dbus.Variant{
-				"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/track/1")),
-				"xesam:title":   dbus.MakeVariant(p.Name),
-				"xesam:artist":  dbus.MakeVariant([]string{e.Text}),
-				"mpris:artUrl":  dbus.MakeVariant(e.Img),
-			}),
+			"Metadata":       dbus.MakeVariant(p.metadata(e.Text, e.Img)),
 		})
 
 		prev = &e
 	}
 }
 
+func (p *TimerPlayer) metadata(text string, img string) map[string]dbus.Variant {
+	metadata := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackId),
+		"xesam:title":   dbus.MakeVariant(p.Name),
+		"xesam:artist":  dbus.MakeVariant([]string{text}),
+		"mpris:artUrl":  dbus.MakeVariant(img),
+	}
+
+	// a stopwatch has no length
+	if !p.isStopwatch() {
+		metadata["mpris:length"] = dbus.MakeVariant(p.duration.Microseconds())
+	}
+
+	if len(p.laps) > 0 {
+		metadata["xesam:album"] = dbus.MakeVariant(p.lapAlbum())
+	}
+
+	if p.isAlarm() {
+		metadata["xesam:album"] = dbus.MakeVariant("Alarm at " + FormatAlarm(p.startTime.Add(p.duration+p.pausedFor)))
+	}
+
+	if p.isSequence() {
+		metadata["xesam:title"] = dbus.MakeVariant(p.PhaseTitle())
+		metadata["xesam:album"] = dbus.MakeVariant(p.phaseAlbum())
+	}
+
+	return metadata
+}
+
+func (p *TimerPlayer) isAlarm() bool {
+	return !p.Options.AlarmAt.IsZero()
+}
+
+// now keeps the monotonic reading, which stops during a suspend and ignores clock changes.
+// Alarms and the timers counting the suspend drop it and follow the wall clock.
+func (p *TimerPlayer) now() time.Time {
+	if p.wallClock {
+		return time.Now().Round(0)
+	}
+
+	return time.Now()
+}
+
+// resume either lets the timer finish right away if the time was up meanwhile (IsMissed),
+// or only saves the new deadline: the monotonic clock already left the suspend out.
+func (p *TimerPlayer) resume() {
+	slept := time.Now().Round(0).Sub(p.sleptAt.Round(0))
+	p.sleptAt = time.Time{}
+
+	if !p.wallClock {
+		log.Printf("resumed after %s, not counted", slept.Round(time.Second))
+		p.save()
+		return
+	}
+
+	log.Printf("resumed after %s", slept.Round(time.Second))
+	if !p.isStopwatch() && p.elapsed() >= p.duration {
+		p.IsMissed = true
+	}
+}
+
+// elapsed is the current position of the timer, pauses excluded
+func (p *TimerPlayer) elapsed() time.Duration {
+	elapsed := time.Since(p.startTime) - p.pausedFor
+	if p.isPaused {
+		elapsed -= time.Since(p.pausedAt)
+	}
+
+	if p.isStopwatch() {
+		return max(elapsed, 0)
+	}
+
+	return min(max(elapsed, 0), p.duration)
+}
+
+// seekTo moves the timer to the given position, seeking to the very end finishes it.
+// A stopwatch can't seek.
+func (p *TimerPlayer) seekTo(position time.Duration) {
+	if p.isStopwatch() {
+		return
+	}
+
+	position = min(max(position, 0), p.duration)
+
+	now := p.now()
+	p.startTime = now.Add(-position)
+	p.pausedFor = 0
+	if p.isPaused {
+		p.pausedAt = now
+	}
+
+	err := p.conn.Emit(p.objectPath, "org.mpris.MediaPlayer2.Player.Seeked", position.Microseconds())
+	if err != nil {
+		log.Printf("emit seeked: %v", err)
+	}
+
+	p.rearmWarnings()
+	p.save()
+}
+
 func (p *TimerPlayer) exportInterfaces() error {
 	if err := p.conn.Export(p, p.objectPath, "org.mpris.MediaPlayer2"); err != nil {
 		return err
 	}
 
-	if err := p.conn.Export(p, p.objectPath, "org.mpris.MediaPlayer2.Player"); err != nil {
+	// Seek would clash with io.Seeker for go vet, hence SeekBy
+	if err := p.conn.ExportWithMap(p, map[string]string{"SeekBy": "Seek"}, p.objectPath, "org.mpris.MediaPlayer2.Player"); err != nil {
 		return err
 	}
 
@@ -218,14 +530,111 @@ func (p *TimerPlayer) emitPropertiesChanged(iface string, changed map[string]dbu
 	}
 }
 
+// emitControl sends a signal of the private control interface
+func (p *TimerPlayer) emitControl(signal string, args ...any) {
+	err := p.conn.Emit(ControlPath, ControlIface+"."+signal, args...)
+	if err != nil {
+		log.Printf("emit %s: %v", signal, err)
+	}
+}
+
+// AddTime extends the timer, negative values shorten it down to the current position
+func (p *TimerPlayer) AddTime(d time.Duration) error {
+	if p.isStopwatch() {
+		return fmt.Errorf("a stopwatch has no duration to change")
+	}
+
+	p.duration = max(p.duration+d, p.elapsed(), time.Second)
+	p.rearmWarnings()
+	p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
+		"Metadata": dbus.MakeVariant(p.metadata(FormatDuration(p.duration-p.elapsed()), p.img)),
+	})
+
+	p.save()
+	return nil
+}
+
+// Rearm prepares a finished timer to run again for the given duration (e.g. snooze),
+// with the same id, title and options. Start it (or add it to the daemon) as usual afterward.
+func (p *TimerPlayer) Rearm(d time.Duration) {
+	p.reset()
+	p.Options.Sequence = nil
+	p.duration = d
+}
+
+// Restart prepares a finished timer to run again from the very beginning, like Rearm does.
+// A sequence starts over from its first phase, an alarm runs for as long as it originally did.
+func (p *TimerPlayer) Restart() {
+	p.reset()
+	p.Options.Sequence = p.initialSteps
+	p.duration = p.initial
+	if p.isSequence() {
+		p.duration = p.Options.Sequence[0].Duration
+	}
+}
+
+func (p *TimerPlayer) reset() {
+	p.Silence()
+	<-p.released
+
+	p.silence = make(chan struct{})
+	p.silenceOnce = sync.Once{}
+	p.released = make(chan struct{})
+	p.Done = make(chan struct{}, 1)
+	p.tickerDone = make(chan struct{}, 1)
+	p.emitter = make(chan PropsChangedEvent, 1)
+	p.destroyOnce = sync.Once{}
+	p.IsFinished = false
+	p.IsCancelled = false
+	p.IsMissed = false
+	p.keepState = false
+
+	// not an alarm anymore
+	p.Options.AlarmAt = time.Time{}
+	p.phase = 0
+	p.restoredAt = 0
+	p.pausedFor = 0
+	p.isPaused = false
+	p.playbackStatus = "Playing"
+	p.progress = 0
+	p.frame = Frame{}
+	p.lastEvent = nil
+}
+
+// Info of a stopwatch has the elapsed time as the duration and nothing remaining
+func (p *TimerPlayer) Info() TimerInfo {
+	if p.isStopwatch() {
+		return TimerInfo{
+			Id:       p.Id,
+			Title:    p.Name,
+			Status:   p.playbackStatus,
+			Duration: int64(p.elapsed() / time.Second),
+		}
+	}
+
+	return TimerInfo{
+		Id:        p.Id,
+		Title:     p.Name,
+		Status:    p.playbackStatus,
+		Duration:  int64(p.duration / time.Second),
+		Remaining: int64((p.duration - p.elapsed()).Round(time.Second) / time.Second),
+	}
+}
+
 func (p *TimerPlayer) Raise() *dbus.Error { return nil }
-func (p *TimerPlayer) Quit() *dbus.Error  { os.Exit(0); return nil }
+func (p *TimerPlayer) Quit() *dbus.Error  { p.Stop(); return nil }
 
+// PlayPause silences a ringing timer, as well as Pause, Stop and Next do
 func (p *TimerPlayer) PlayPause() *dbus.Error {
+	if p.IsFinished {
+		p.Silence()
+		return nil
+	}
+
 	if p.isPaused {
 		p.pausedFor += time.Since(p.pausedAt)
 	} else {
-		p.pausedAt = time.Now()
+		p.pausedAt = p.now()
 	}
 
 	p.isPaused = !p.isPaused
@@ -235,21 +644,88 @@ func (p *TimerPlayer) PlayPause() *dbus.Error {
 		"PlaybackStatus": dbus.MakeVariant(p.playbackStatus),
 	})
 
+	p.save()
 	p.broadcast()
 	return nil
 }
 
+func (p *TimerPlayer) Play() *dbus.Error {
+	if p.isPaused {
+		return p.PlayPause()
+	}
+
+	return nil
+}
+
+func (p *TimerPlayer) Pause() *dbus.Error {
+	if !p.isPaused {
+		return p.PlayPause()
+	}
+
+	return nil
+}
+
+// Previous restarts the timer, or the current phase of a sequence, or resets a stopwatch
 func (p *TimerPlayer) Previous() *dbus.Error {
-	p.startTime = time.Now()
+	p.startTime = p.now()
 	p.pausedFor = 0
+	p.laps = nil
 	p.isPaused = false
 	p.playbackStatus = "Playing"
+	p.rearmWarnings()
+	p.save()
 	p.broadcast()
 	return nil
 }
 
-func (p *TimerPlayer) Next() *dbus.Error { os.Exit(1); return nil }
-func (p *TimerPlayer) Stop() *dbus.Error { os.Exit(1); return nil }
+// Next skips to the next phase of a sequence, records a lap of a stopwatch,
+// otherwise finishes the timer right away
+func (p *TimerPlayer) Next() *dbus.Error {
+	if p.IsFinished {
+		p.Silence()
+		return nil
+	}
+
+	if p.isStopwatch() {
+		_ = p.Lap()
+		return nil
+	}
+
+	if p.hasNextPhase() {
+		p.nextPhase(p.now())
+		return nil
+	}
+
+	p.seekTo(p.duration)
+	return nil
+}
+
+func (p *TimerPlayer) Stop() *dbus.Error {
+	if p.IsFinished {
+		p.Silence()
+		return nil
+	}
+
+	p.Cancel()
+	return nil
+}
+
+// SeekBy is the Seek method of MPRIS, offset is in microseconds, negative values seek backwards
+func (p *TimerPlayer) SeekBy(offset int64) *dbus.Error {
+	p.seekTo(p.elapsed() + time.Duration(offset)*time.Microsecond)
+	return nil
+}
+
+// SetPosition is ignored for a stale track id or a position out of range, as the spec says
+func (p *TimerPlayer) SetPosition(track dbus.ObjectPath, position int64) *dbus.Error {
+	pos := time.Duration(position) * time.Microsecond
+	if track != trackId || pos < 0 || pos > p.duration {
+		return nil
+	}
+
+	p.seekTo(pos)
+	return nil
+}
 
 func (p *TimerPlayer) Get(iface, prop string) (dbus.Variant, *dbus.Error) {
 	switch iface {
@@ -273,9 +749,17 @@ func (p *TimerPlayer) Get(iface, prop string) (dbus.Variant, *dbus.Error) {
 		case "CanPause":
 			return dbus.MakeVariant(true), nil
 		case "CanSeek":
-			return dbus.MakeVariant(false), nil
+			return dbus.MakeVariant(!p.isStopwatch()), nil
 		case "CanControl":
 			return dbus.MakeVariant(true), nil
+		case "Metadata":
+			return dbus.MakeVariant(p.metadata(p.progressText, p.img)), nil
+		case "Position":
+			return dbus.MakeVariant(p.elapsed().Microseconds()), nil
+		case "Rate", "MinimumRate", "MaximumRate":
+			return dbus.MakeVariant(1.0), nil
+		case "Volume":
+			return dbus.MakeVariant(Overrides.Volume), nil
 		}
 	}
 	return dbus.Variant{}, nil
@@ -293,16 +777,49 @@ func (p *TimerPlayer) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error
 		props["CanGoPrevious"] = dbus.MakeVariant(true)
 		props["CanPlay"] = dbus.MakeVariant(true)
 		props["CanPause"] = dbus.MakeVariant(true)
-		props["CanSeek"] = dbus.MakeVariant(false)
+		props["CanSeek"] = dbus.MakeVariant(!p.isStopwatch())
 		props["CanControl"] = dbus.MakeVariant(true)
+		props["Metadata"] = dbus.MakeVariant(p.metadata(p.progressText, p.img))
+		props["Position"] = dbus.MakeVariant(p.elapsed().Microseconds())
+		props["Rate"] = dbus.MakeVariant(1.0)
+		props["MinimumRate"] = dbus.MakeVariant(1.0)
+		props["MaximumRate"] = dbus.MakeVariant(1.0)
+		props["Volume"] = dbus.MakeVariant(Overrides.Volume)
 	}
 	return props, nil
 }
 
-func (p *TimerPlayer) Set(_, _ string, _ dbus.Variant) *dbus.Error {
+// Set only supports Volume, the rate of a timer is fixed
+func (p *TimerPlayer) Set(iface, prop string, value dbus.Variant) *dbus.Error {
+	if iface != "org.mpris.MediaPlayer2.Player" || prop != "Volume" {
+		return nil
+	}
+
+	volume, ok := value.Value().(float64)
+	if !ok {
+		return dbus.MakeFailedError(fmt.Errorf("volume must be a double"))
+	}
+
+	Overrides.Volume = math.Max(0, math.Min(1, volume))
+	p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
+		"Volume": dbus.MakeVariant(Overrides.Volume),
+	})
+
 	return nil
 }
 
+// newFrame is what the progress image shows now
+func (p *TimerPlayer) newFrame(left time.Duration, duration time.Duration) Frame {
+	frame := Frame{Progress: p.progress, Left: left, Duration: duration, Title: p.Name}
+	if p.isSequence() {
+		frame.Phase = p.PhaseTitle()
+		frame.Step = p.phase + 1
+		frame.Steps = len(p.Options.Sequence)
+	}
+
+	return frame
+}
+
 func (p *TimerPlayer) broadcast() {
 	if p.progress >= 100 {
 		return
@@ -322,6 +839,7 @@ func (p *TimerPlayer) broadcast() {
 		ev := PropsChangedEvent{
 			Img:      img,
 			Text:     p.progressText,
+			Frame:    p.frame,
 			IsPaused: p.isPaused && !p.IsFinished,
 		}
 
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxCountdown is the longest countdown, there are no samples beyond "10"
+const maxCountdown = 10
+
+var (
+	// countdownSamples are read once from DataDir/countdown, nil if there's none
+	countdownSamples = map[int][]byte{}
+	countdownMu      sync.Mutex
+)
+
+// cue plays the running timer's tick, chime or countdown as the seconds go by,
+// left is the number of seconds remaining (elapsed for a stopwatch).
+// Each depends on its own option only, not on the alarm sound. One cue plays a second:
+// the countdown replaces the chime and the tick, the chime replaces the tick.
+func (p *TimerPlayer) cue(left int64) {
+	// nothing at the very start, and nothing while paused or skipped through
+	if p.isPaused || p.elapsed() < time.Second {
+		return
+	}
+
+	switch {
+	case !p.isStopwatch() && left > 0 && left <= int64(p.Options.Countdown) && Overrides.CountdownVolume > 0:
+		go playCue(countdownCue(int(left)), Overrides.CountdownVolume)
+	case p.Options.Chime && left > 0 && left%60 == 0 && Overrides.ChimeVolume > 0:
+		go playCue(chime(chimeTone, chimeLength), Overrides.ChimeVolume)
+	case p.Options.Tick && Overrides.TickVolume > 0:
+		go playCue(beep(tickTone, tickPulse, 0, 1), Overrides.TickVolume)
+	}
+}
+
+// playCue plays at the volume of the cue, relative to the sound volume
+func playCue(dec decoder, volume float64) {
+	if _, err := play(dec, nil, func() float64 { return Overrides.Volume * volume }); err != nil {
+		log.Printf("playing cue: %v", err)
+	}
+}
+
+// countdownCue is the sample of the number from DataDir/countdown (1.oga, 2.oga, …)
+// if the user has put one there, otherwise a beep that gets higher towards the end
+func countdownCue(n int) decoder {
+	if data := countdownSample(n); data != nil {
+		if dec, err := newDecoder(data); err == nil {
+			return dec
+		}
+	}
+
+	return beep(countdownTone*(1+float64(maxCountdown-n)/maxCountdown), countdownPulse, 0, 1)
+}
+
+func countdownSample(n int) []byte {
+	countdownMu.Lock()
+	defer countdownMu.Unlock()
+
+	if data, ok := countdownSamples[n]; ok {
+		return data
+	}
+
+	countdownSamples[n] = nil
+	for _, ext := range soundExtensions {
+		data, err := os.ReadFile(path.Join(DataDir, "countdown", strconv.Itoa(n)+ext))
+		if err == nil {
+			countdownSamples[n] = data
+			return data
+		}
+	}
+
+	return nil
+}
+
+// PreviewTick plays the tick at its volume
+func PreviewTick() {
+	playCue(beep(tickTone, tickPulse, 0, 1), Overrides.TickVolume)
+}
+
+// PreviewChime plays the chime at its volume
+func PreviewChime() {
+	playCue(chime(chimeTone, chimeLength), Overrides.ChimeVolume)
+}
+
+// PreviewCountdown plays the last three numbers of the countdown at its volume
+func PreviewCountdown() {
+	for n := 3; n > 0; n-- {
+		started := time.Now()
+		playCue(countdownCue(n), Overrides.CountdownVolume)
+		time.Sleep(time.Second - time.Since(started))
+	}
+}
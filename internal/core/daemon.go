@@ -0,0 +1,263 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"log"
+	"strings"
+	"time"
+)
+
+const DaemonName = "org.mpris.MediaPlayer2." + AppId
+
+var (
+	ErrNoDaemon = errors.New("daemon is not running")
+	// ErrNotForwarded is returned by HandOff for the flags the daemon has its own values of
+	ErrNotForwarded = errors.New("can't be passed to the daemon")
+)
+
+// Daemon hosts any number of timers in a single process,
+// each of them is still a separate MPRIS player
+type Daemon struct {
+	*Control
+}
+
+func NewDaemon() *Daemon {
+	return &Daemon{Control: newControl(true)}
+}
+
+func (d *Daemon) Start() error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return fmt.Errorf("connect to session bus: %w", err)
+	}
+
+	d.conn = conn
+	reply, err := conn.RequestName(DaemonName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("request bus: %w", err)
+	}
+
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("request bus: daemon is already running")
+	}
+
+	if err = d.export(); err != nil {
+		return fmt.Errorf("export interfaces: %w", err)
+	}
+
+	if err = exportIdlePlayer(conn); err != nil {
+		return fmt.Errorf("export interfaces: %w", err)
+	}
+
+	return nil
+}
+
+// Destroy cancels all the timers and releases the bus name
+func (d *Daemon) Destroy() {
+	d.stop((*TimerPlayer).Cancel)
+}
+
+// Detach stops the timers but keeps their state, so that they are restored on the next start
+func (d *Daemon) Detach() {
+	d.stop((*TimerPlayer).Detach)
+}
+
+func (d *Daemon) stop(stopTimer func(*TimerPlayer)) {
+	d.mu.Lock()
+	timers := make([]*TimerPlayer, 0, len(d.timers))
+	for _, timer := range d.timers {
+		timers = append(timers, timer)
+	}
+	d.mu.Unlock()
+
+	for _, timer := range timers {
+		stopTimer(timer)
+		<-timer.Done
+	}
+
+	if _, err := d.conn.ReleaseName(DaemonName); err != nil {
+		log.Printf("release bus name: %v", err)
+	}
+
+	_ = d.conn.Close()
+}
+
+// HandOff passes a new timer to the running daemon, returns ErrNoDaemon if there is none.
+// The flags of the whole process, e.g. -color, aren't per timer: ErrNotForwarded then.
+func HandOff(seconds int, title string, opts TimerOptions) (string, error) {
+	if flags := ProcessFlags(); len(flags) > 0 {
+		return "", fmt.Errorf("-%s %w", strings.Join(flags, ", -"), ErrNotForwarded)
+	}
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return "", fmt.Errorf("connect to session bus: %w", err)
+	}
+
+	var hasOwner bool
+	err = conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, DaemonName).Store(&hasOwner)
+	if err != nil {
+		return "", err
+	}
+
+	if !hasOwner {
+		return "", ErrNoDaemon
+	}
+
+	var id string
+	err = conn.Object(DaemonName, ControlPath).
+		Call(ControlIface+".Create", 0, uint32(seconds), title, opts.variants()).
+		Store(&id)
+
+	return id, err
+}
+
+func (o *TimerOptions) apply(options map[string]dbus.Variant) {
+	for key, value := range options {
+		var err error
+		switch key {
+		case "text":
+			err = value.Store(&o.Text)
+		case "notify":
+			err = value.Store(&o.Notify)
+		case "sound":
+			err = value.Store(&o.Sound)
+		case "stopwatch":
+			err = value.Store(&o.Stopwatch)
+		case "tick":
+			err = value.Store(&o.Tick)
+		case "chime":
+			err = value.Store(&o.Chime)
+		case "countdown":
+			var countdown uint32
+			if err = value.Store(&countdown); err == nil {
+				o.Countdown = min(int(countdown), maxCountdown)
+			}
+		case "sequence":
+			var sequence string
+			if err = value.Store(&sequence); err == nil {
+				o.Sequence, err = ParseSequence(sequence)
+			}
+		case "warnings":
+			var warnings string
+			if err = value.Store(&warnings); err == nil {
+				o.Warnings, err = ParseWarnings(warnings)
+			}
+		case "at":
+			var at int64
+			if err = value.Store(&at); err == nil {
+				o.AlarmAt = time.Unix(at, 0)
+			}
+		default:
+			log.Printf("unknown timer option: %s", key)
+		}
+
+		if err != nil {
+			log.Printf("timer option %s: %v", key, err)
+		}
+	}
+}
+
+func (o *TimerOptions) variants() map[string]dbus.Variant {
+	options := map[string]dbus.Variant{
+		"text":   dbus.MakeVariant(o.Text),
+		"notify": dbus.MakeVariant(o.Notify),
+		"sound":  dbus.MakeVariant(o.Sound),
+	}
+
+	if !o.AlarmAt.IsZero() {
+		options["at"] = dbus.MakeVariant(o.AlarmAt.Unix())
+	}
+
+	if o.Stopwatch {
+		options["stopwatch"] = dbus.MakeVariant(true)
+	}
+
+	if len(o.Sequence) > 0 {
+		options["sequence"] = dbus.MakeVariant(FormatSequence(o.Sequence))
+	}
+
+	if o.Tick {
+		options["tick"] = dbus.MakeVariant(true)
+	}
+
+	if o.Chime {
+		options["chime"] = dbus.MakeVariant(true)
+	}
+
+	if o.Countdown > 0 {
+		options["countdown"] = dbus.MakeVariant(uint32(o.Countdown))
+	}
+
+	if len(o.Warnings) > 0 {
+		options["warnings"] = dbus.MakeVariant(FormatWarnings(o.Warnings))
+	}
+
+	return options
+}
+
+// idlePlayer is the MPRIS player of the daemon's own name, every timer has a player of its own.
+// It can't play, so the shells don't show it.
+type idlePlayer struct{}
+
+func exportIdlePlayer(conn *dbus.Conn) error {
+	player := idlePlayer{}
+	for _, iface := range []string{"org.mpris.MediaPlayer2", "org.mpris.MediaPlayer2.Player", "org.freedesktop.DBus.Properties"} {
+		if err := conn.Export(player, "/org/mpris/MediaPlayer2", iface); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (idlePlayer) Raise() *dbus.Error     { return nil }
+func (idlePlayer) Quit() *dbus.Error      { return nil }
+func (idlePlayer) Play() *dbus.Error      { return nil }
+func (idlePlayer) Pause() *dbus.Error     { return nil }
+func (idlePlayer) PlayPause() *dbus.Error { return nil }
+func (idlePlayer) Stop() *dbus.Error      { return nil }
+func (idlePlayer) Next() *dbus.Error      { return nil }
+func (idlePlayer) Previous() *dbus.Error  { return nil }
+
+func (p idlePlayer) Get(iface, prop string) (dbus.Variant, *dbus.Error) {
+	props, _ := p.GetAll(iface)
+	if value, ok := props[prop]; ok {
+		return value, nil
+	}
+
+	return dbus.Variant{}, dbus.MakeFailedError(fmt.Errorf("unknown property %s.%s", iface, prop))
+}
+
+func (idlePlayer) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	props := make(map[string]dbus.Variant)
+	switch iface {
+	case "org.mpris.MediaPlayer2":
+		props["Identity"] = dbus.MakeVariant(AppName)
+		props["DesktopEntry"] = dbus.MakeVariant(AppId)
+		props["CanQuit"] = dbus.MakeVariant(false)
+		props["CanRaise"] = dbus.MakeVariant(false)
+		props["HasTrackList"] = dbus.MakeVariant(false)
+		props["SupportedUriSchemes"] = dbus.MakeVariant([]string{})
+		props["SupportedMimeTypes"] = dbus.MakeVariant([]string{})
+	case "org.mpris.MediaPlayer2.Player":
+		props["PlaybackStatus"] = dbus.MakeVariant("Stopped")
+		props["Metadata"] = dbus.MakeVariant(map[string]dbus.Variant{})
+		props["Position"] = dbus.MakeVariant(int64(0))
+		props["Rate"] = dbus.MakeVariant(1.0)
+		props["MinimumRate"] = dbus.MakeVariant(1.0)
+		props["MaximumRate"] = dbus.MakeVariant(1.0)
+		props["Volume"] = dbus.MakeVariant(Overrides.Volume)
+		for _, can := range []string{"CanGoNext", "CanGoPrevious", "CanPlay", "CanPause", "CanSeek", "CanControl"} {
+			props[can] = dbus.MakeVariant(false)
+		}
+	}
+
+	return props, nil
+}
+
+func (idlePlayer) Set(iface, prop string, value dbus.Variant) *dbus.Error {
+	return nil
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/jfreymuth/oggvorbis"
+	"github.com/mewkiz/flac"
+	"io"
+	"math"
+)
+
+// decoder produces 16-bit little endian stereo PCM, the format oto plays
+type decoder interface {
+	io.Reader
+	SampleRate() int
+}
+
+// newDecoder detects the format by the content, the file extension doesn't matter
+func newDecoder(data []byte) (decoder, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return newOggDecoder(data)
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return newFlacDecoder(data)
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WAVE":
+		return newWavDecoder(data)
+	case isMP3(data):
+		return mp3.NewDecoder(bytes.NewReader(data))
+	}
+
+	return nil, fmt.Errorf("unsupported sound format, expected MP3, Ogg Vorbis, FLAC or WAV")
+}
+
+// isMP3 recognizes an ID3v2 tag or an MPEG audio frame sync
+func isMP3(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("ID3")) {
+		return true
+	}
+
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0
+}
+
+// pcmStream converts the samples of a decoder library to stereo PCM,
+// next returns the interleaved samples of the following chunk, scaled to [-1, 1]
+type pcmStream struct {
+	sampleRate int
+	channels   int
+	next       func() ([]float32, error)
+	buf        []byte
+	err        error
+}
+
+func (s *pcmStream) SampleRate() int {
+	return s.sampleRate
+}
+
+func (s *pcmStream) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+
+		var samples []float32
+		samples, s.err = s.next()
+		s.buf = s.stereo(samples)
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// stereo duplicates a mono channel and drops the channels after the first two
+func (s *pcmStream) stereo(samples []float32) []byte {
+	frames := len(samples) / s.channels
+	out := make([]byte, 0, frames*4)
+	for i := range frames {
+		left := samples[i*s.channels]
+		right := left
+		if s.channels > 1 {
+			right = samples[i*s.channels+1]
+		}
+
+		out = binary.LittleEndian.AppendUint16(out, uint16(toInt16(left)))
+		out = binary.LittleEndian.AppendUint16(out, uint16(toInt16(right)))
+	}
+
+	return out
+}
+
+func toInt16(sample float32) int16 {
+	return int16(math.Max(-1, math.Min(1, float64(sample))) * math.MaxInt16)
+}
+
+func newOggDecoder(data []byte) (decoder, error) {
+	reader, err := oggvorbis.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode ogg: %w", err)
+	}
+
+	buf := make([]float32, 4096*reader.Channels())
+	return &pcmStream{
+		sampleRate: reader.SampleRate(),
+		channels:   reader.Channels(),
+		next: func() ([]float32, error) {
+			n, err := reader.Read(buf)
+			return buf[:n], err
+		},
+	}, nil
+}
+
+func newFlacDecoder(data []byte) (decoder, error) {
+	stream, err := flac.New(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode flac: %w", err)
+	}
+
+	channels := int(stream.Info.NChannels)
+	scale := float32(int64(1) << (stream.Info.BitsPerSample - 1))
+	return &pcmStream{
+		sampleRate: int(stream.Info.SampleRate),
+		channels:   channels,
+		next: func() ([]float32, error) {
+			frame, err := stream.ParseNext()
+			if err != nil {
+				return nil, err
+			}
+
+			samples := make([]float32, 0, int(frame.BlockSize)*channels)
+			for i := range int(frame.BlockSize) {
+				for _, subframe := range frame.Subframes {
+					samples = append(samples, float32(subframe.Samples[i])/scale)
+				}
+			}
+
+			return samples, nil
+		},
+	}, nil
+}
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// newWavDecoder supports integer PCM of 8 to 32 bits and 32-bit float
+func newWavDecoder(data []byte) (decoder, error) {
+	var format, channels, bits uint16
+	var sampleRate uint32
+	var pcm []byte
+
+	for chunks := data[12:]; len(chunks) >= 8 && pcm == nil; {
+		id := string(chunks[:4])
+		size := int(binary.LittleEndian.Uint32(chunks[4:8]))
+		chunks = chunks[8:]
+		if size > len(chunks) {
+			// a truncated file, play what's there
+			size = len(chunks)
+		}
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("decode wav: malformed fmt chunk")
+			}
+
+			format = binary.LittleEndian.Uint16(chunks[0:2])
+			channels = binary.LittleEndian.Uint16(chunks[2:4])
+			sampleRate = binary.LittleEndian.Uint32(chunks[4:8])
+			bits = binary.LittleEndian.Uint16(chunks[14:16])
+			if format == wavFormatExtensible && size >= 26 {
+				format = binary.LittleEndian.Uint16(chunks[24:26])
+			}
+		case "data":
+			pcm = chunks[:size]
+		}
+
+		// chunks are padded to an even size
+		chunks = chunks[min(size+size%2, len(chunks)):]
+	}
+
+	if channels == 0 || sampleRate == 0 {
+		return nil, errors.New("decode wav: missing fmt chunk")
+	}
+
+	if pcm == nil {
+		return nil, errors.New("decode wav: missing data chunk")
+	}
+
+	var sample func([]byte) float32
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		sample = func(b []byte) float32 { return (float32(b[0]) - 128) / 128 }
+	case format == wavFormatPCM && bits == 16:
+		sample = func(b []byte) float32 { return float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case format == wavFormatPCM && bits == 24:
+		sample = func(b []byte) float32 {
+			return float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)) / (1 << 31)
+		}
+	case format == wavFormatPCM && bits == 32:
+		sample = func(b []byte) float32 { return float32(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case format == wavFormatFloat && bits == 32:
+		sample = func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }
+	default:
+		return nil, fmt.Errorf("decode wav: unsupported format %d with %d bits", format, bits)
+	}
+
+	width := int(bits / 8)
+	chunk := 4096 * int(channels) * width
+	return &pcmStream{
+		sampleRate: int(sampleRate),
+		channels:   int(channels),
+		next: func() ([]float32, error) {
+			if len(pcm) < width {
+				return nil, io.EOF
+			}
+
+			part := pcm[:min(chunk, len(pcm)-len(pcm)%width)]
+			pcm = pcm[len(part):]
+
+			samples := make([]float32, len(part)/width)
+			for i := range samples {
+				samples[i] = sample(part[i*width:])
+			}
+
+			return samples, nil
+		},
+	}, nil
+}
+
+// resampler converts 16-bit stereo PCM to another sample rate by linear interpolation
+type resampler struct {
+	src     *bufio.Reader
+	step    float64
+	pos     float64
+	prev    [2]int16
+	next    [2]int16
+	drained bool
+	done    bool
+}
+
+func newResampler(dec decoder, rate int) *resampler {
+	r := &resampler{
+		src:  bufio.NewReaderSize(dec, 16*1024),
+		step: float64(dec.SampleRate()) / float64(rate),
+	}
+
+	// prev and next are the first two frames, so that the output starts right at the first one
+	r.done = !r.advance() || !r.advance()
+	return r
+}
+
+func (r *resampler) Read(p []byte) (int, error) {
+	n := 0
+	for n+4 <= len(p) {
+		for r.pos >= 1 && !r.done {
+			r.done = !r.advance()
+			r.pos--
+		}
+
+		if r.done {
+			break
+		}
+
+		for c := range 2 {
+			sample := float64(r.prev[c]) + (float64(r.next[c])-float64(r.prev[c]))*r.pos
+			binary.LittleEndian.PutUint16(p[n+2*c:], uint16(int16(sample)))
+		}
+
+		n += 4
+		r.pos += r.step
+	}
+
+	if n == 0 && r.done {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// advance reads the next source frame, reports false at the end.
+// The last frame is held for one more period, so that it's played too.
+func (r *resampler) advance() bool {
+	var frame [4]byte
+	if _, err := io.ReadFull(r.src, frame[:]); err != nil {
+		if r.drained {
+			return false
+		}
+
+		r.drained = true
+		r.prev = r.next
+		return true
+	}
+
+	r.prev = r.next
+	r.next = [2]int16{int16(binary.LittleEndian.Uint16(frame[0:])), int16(binary.LittleEndian.Uint16(frame[2:]))}
+	return true
+}
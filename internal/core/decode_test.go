@@ -0,0 +1,202 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+	"testing"
+)
+
+// wavFile builds a RIFF file of the given chunks, each is an id followed by its content
+func wavFile(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, chunk := range chunks {
+		body = append(body, chunk[:4]...)
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(chunk)-4))
+		body = append(body, chunk[4:]...)
+		if len(chunk)%2 == 1 {
+			body = append(body, 0)
+		}
+	}
+
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(data, body...)
+}
+
+func fmtChunk(format uint16, channels uint16, rate uint32, bits uint16) []byte {
+	chunk := []byte("fmt ")
+	chunk = binary.LittleEndian.AppendUint16(chunk, format)
+	chunk = binary.LittleEndian.AppendUint16(chunk, channels)
+	chunk = binary.LittleEndian.AppendUint32(chunk, rate)
+	chunk = binary.LittleEndian.AppendUint32(chunk, rate*uint32(channels*bits/8))
+	chunk = binary.LittleEndian.AppendUint16(chunk, channels*bits/8)
+	return binary.LittleEndian.AppendUint16(chunk, bits)
+}
+
+func int16Samples(samples ...int16) []byte {
+	var data []byte
+	for _, sample := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(sample))
+	}
+
+	return data
+}
+
+// decodeAll returns the stereo frames of the file as interleaved samples
+func decodeAll(t *testing.T, data []byte) []int16 {
+	t.Helper()
+	dec, err := newDecoder(data)
+	if err != nil {
+		t.Fatalf("newDecoder: %v", err)
+	}
+
+	pcm, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(pcm[2*i:]))
+	}
+
+	return samples
+}
+
+func TestWavStereo16(t *testing.T) {
+	data := wavFile(fmtChunk(wavFormatPCM, 2, 44100, 16), append([]byte("data"), int16Samples(1000, -1000, 16384, -16384)...))
+	samples := decodeAll(t, data)
+	expected := []int16{999, -999, 16383, -16383}
+	if !slices.Equal(samples, expected) {
+		t.Errorf("samples = %v, expected %v", samples, expected)
+	}
+}
+
+func TestWavMono8(t *testing.T) {
+	data := wavFile(fmtChunk(wavFormatPCM, 1, 8000, 8), []byte("data\x00\x80\xc0"))
+	dec, err := newDecoder(data)
+	if err != nil {
+		t.Fatalf("newDecoder: %v", err)
+	}
+
+	if dec.SampleRate() != 8000 {
+		t.Errorf("SampleRate() = %d, expected 8000", dec.SampleRate())
+	}
+
+	// mono is duplicated to both channels
+	samples := decodeAll(t, data)
+	expected := []int16{-32767, -32767, 0, 0, 16383, 16383}
+	if !slices.Equal(samples, expected) {
+		t.Errorf("samples = %v, expected %v", samples, expected)
+	}
+}
+
+func TestWavOddChunkPadding(t *testing.T) {
+	// the padding byte after the odd LIST chunk isn't the start of the next one
+	data := wavFile(fmtChunk(wavFormatPCM, 1, 8000, 16), []byte("LISTabc"), []byte("junk"), append([]byte("data"), int16Samples(8192)...))
+	samples := decodeAll(t, data)
+	expected := []int16{8191, 8191}
+	if !slices.Equal(samples, expected) {
+		t.Errorf("samples = %v, expected %v", samples, expected)
+	}
+}
+
+func TestWavExtensibleFloat(t *testing.T) {
+	format := fmtChunk(wavFormatExtensible, 1, 48000, 32)
+	format = binary.LittleEndian.AppendUint16(format, 22)
+	format = binary.LittleEndian.AppendUint16(format, 32)
+	format = binary.LittleEndian.AppendUint32(format, 4)
+	format = binary.LittleEndian.AppendUint16(format, wavFormatFloat)
+	format = append(format, make([]byte, 14)...)
+
+	data := wavFile(format, []byte("data\x00\x00\x00\x3f"))
+	samples := decodeAll(t, data)
+	expected := []int16{16383, 16383}
+	if !slices.Equal(samples, expected) {
+		t.Errorf("samples = %v, expected %v", samples, expected)
+	}
+}
+
+func TestWavTruncatedData(t *testing.T) {
+	data := wavFile(fmtChunk(wavFormatPCM, 1, 8000, 16), append([]byte("data"), int16Samples(100, 200, 300)...))
+	// the header promises more than there is, and the last sample is cut in half
+	binary.LittleEndian.PutUint32(data[len(data)-10:], 1000)
+	data = data[:len(data)-1]
+
+	samples := decodeAll(t, data)
+	expected := []int16{99, 99, 199, 199}
+	if !slices.Equal(samples, expected) {
+		t.Errorf("samples = %v, expected %v", samples, expected)
+	}
+}
+
+func TestWavUnsupported(t *testing.T) {
+	tests := map[string][]byte{
+		"adpcm":      wavFile(fmtChunk(2, 1, 8000, 4), []byte("data\x00\x00")),
+		"12 bits":    wavFile(fmtChunk(wavFormatPCM, 1, 8000, 12), []byte("data\x00\x00")),
+		"float64":    wavFile(fmtChunk(wavFormatFloat, 1, 8000, 64), []byte("data\x00\x00")),
+		"no fmt":     wavFile(append([]byte("data"), int16Samples(1)...)),
+		"no data":    wavFile(fmtChunk(wavFormatPCM, 1, 8000, 16)),
+		"short fmt":  wavFile([]byte("fmt \x01\x00\x01\x00"), []byte("data\x00\x00")),
+		"not a riff": []byte("RIFX\x00\x00\x00\x00WAVE"),
+	}
+
+	for name, data := range tests {
+		if _, err := newDecoder(data); err == nil {
+			t.Errorf("%s: newDecoder succeeded, expected an error", name)
+		}
+	}
+}
+
+// pcmFrames is a decoder of the given mono frames, duplicated to stereo
+type pcmFrames struct {
+	*bytes.Reader
+	rate int
+}
+
+func (f pcmFrames) SampleRate() int {
+	return f.rate
+}
+
+func newPCMFrames(rate int, frames ...int16) pcmFrames {
+	var data []byte
+	for _, frame := range frames {
+		data = append(data, int16Samples(frame, frame)...)
+	}
+
+	return pcmFrames{bytes.NewReader(data), rate}
+}
+
+func TestResampler(t *testing.T) {
+	tests := []struct {
+		name     string
+		frames   []int16
+		rate     int
+		expected []int16
+	}{
+		{name: "same rate", frames: []int16{0, 1000, 2000, 3000}, rate: 8000, expected: []int16{0, 1000, 2000, 3000}},
+		{name: "twice the rate", frames: []int16{0, 1000, 2000}, rate: 16000, expected: []int16{0, 500, 1000, 1500, 2000, 2000}},
+		{name: "half the rate", frames: []int16{0, 1000, 2000, 3000}, rate: 4000, expected: []int16{0, 2000}},
+		{name: "single frame", frames: []int16{1234}, rate: 8000, expected: []int16{1234}},
+		{name: "empty", rate: 8000},
+	}
+
+	for _, test := range tests {
+		pcm, err := io.ReadAll(newResampler(newPCMFrames(8000, test.frames...), test.rate))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		// the channels are the same, only the left one is compared
+		var left []int16
+		for i := 0; i+4 <= len(pcm); i += 4 {
+			left = append(left, int16(binary.LittleEndian.Uint16(pcm[i:])))
+		}
+
+		if !slices.Equal(left, test.expected) {
+			t.Errorf("%s: frames = %v, expected %v", test.name, left, test.expected)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"log"
+	"os"
+	"path"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+// MigrateDirs moves the files of the former layout, which kept the cache in
+// ~/.var/app/<AppId>/cache and the saved timers in DataDir for every installation.
+// Each directory is moved once, a marker in StateDir says so.
+func MigrateDirs() {
+	home, _ := os.UserHomeDir()
+	legacyCache := path.Join(home, ".var", "app", AppId, "cache")
+
+	// the same directory within flatpak, and the flatpak's own one if it's installed as well.
+	// Not with -cache-dir, a later launch without it moves the cache.
+	if isDefaultCacheDir() && !isFlatpakInstalled() {
+		migrateOnce("cache", func() {
+			migrateDir(legacyCache, CacheDir)
+			_ = os.Remove(path.Dir(legacyCache))
+		})
+	}
+
+	migrateOnce("timers", func() {
+		migrateDir(path.Join(DataDir, "timers"), timersDir())
+	})
+}
+
+// migrateOnce runs the migration unless its marker exists, then creates the marker
+func migrateOnce(name string, migrate func()) {
+	marker := path.Join(StateDir, "migrated-"+name)
+	if fileExists(marker) {
+		return
+	}
+
+	migrate()
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		log.Printf("migrate %s: %v", name, err)
+	}
+}
+
+// migrateDir moves the entries that don't exist in the destination yet,
+// the source is removed once it's empty
+func migrateDir(from string, to string) {
+	if from == to {
+		return
+	}
+
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return
+	}
+
+	log.Printf("moving %s to %s", from, to)
+	_ = os.MkdirAll(to, 0755)
+	for _, entry := range entries {
+		target := path.Join(to, entry.Name())
+		if fileExists(target) {
+			continue
+		}
+
+		if err = os.Rename(path.Join(from, entry.Name()), target); err != nil {
+			log.Printf("move %s: %v", entry.Name(), err)
+		}
+	}
+
+	// fails unless empty
+	_ = os.Remove(from)
+}
+
+// isDefaultCacheDir is false with -cache-dir
+func isDefaultCacheDir() bool {
+	return CacheDir == appDir(glib.GetUserCacheDir())
+}
+
+func isFlatpakInstalled() bool {
+	home, _ := os.UserHomeDir()
+	for _, dir := range []string{path.Join(home, ".local", "share", "flatpak"), "/var/lib/flatpak"} {
+		if fileExists(path.Join(dir, "app", AppId)) {
+			return true
+		}
+	}
+
+	return false
+}
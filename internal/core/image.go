@@ -3,181 +3,188 @@ package core
 import (
 	"bytes"
 	"fmt"
-	"github.com/srwiley/oksvg"
-	"github.com/srwiley/rasterx"
-	"image"
-	"image/png"
+	"hash/fnv"
+	"html"
 	"log"
 	"math"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
-	"text/template"
+	"time"
 )
 
-var (
-	tpl = template.New("svg")
-
-	cacheLoaded bool
-	cacheMu     sync.RWMutex
-	cache       = make(map[string]struct{})
-
-	// unfortunately PNG is needed for tray icon
-	pngCacheLoaded bool
-	pngCacheMu     sync.RWMutex
-	pngCache       = make(map[string]struct{})
-)
+var cache = newFileCache()
 
 func InitCache() {
 	walk(CacheDir)
-
-	cacheMu.Lock()
-	cacheLoaded = true
-	cacheMu.Unlock()
-
-	pngCacheMu.Lock()
-	pngCacheLoaded = true
-	pngCacheMu.Unlock()
+	cache.setLoaded()
+	EvictCache()
 }
 
-func MakeProgressCircle(progress float64) (string, error) {
-	progress = math.Max(0, math.Min(100, progress))
-	dirname := path.Join(CacheDir, strings.ToUpper(strings.Replace(Overrides.Color, "#", "", 1)))
-	footprint := fmt.Sprintf("sh%v.r%v.%.2f", bool2int(Overrides.HasShadow), bool2int(Overrides.Rounded), progress)
-	filename := path.Join(dirname, footprint+".svg")
-
-	cacheMu.RLock()
-	if cacheLoaded {
-		_, exists := cache[filename]
-		if exists {
-			cacheMu.RUnlock()
-			return filename, nil
-		}
+// MakeProgressCircle writes the SVG of the style for mpris:artUrl, which has to be a file.
+// Use RenderProgress when the image is only needed in-process.
+func MakeProgressCircle(frame Frame) (string, error) {
+	style := Overrides.Style
+	if svgTpls[style] == nil {
+		style = StyleRing
 	}
-	cacheMu.RUnlock()
 
-	if _, err := os.Stat(filename); err == nil {
-		cacheMu.Lock()
-		cache[filename] = struct{}{}
-		cacheMu.Unlock()
+	dirname := colorCacheDir()
+	filename := path.Join(dirname, frameFootprint(style, frame)+".svg")
+	if cache.has(filename) {
 		return filename, nil
 	}
 
-	centerX := width / 2
-	centerY := height / 2
+	if cache.adopt(filename) {
+		return filename, nil
+	}
+
+	frame.Progress = fileProgress(style, frame.Progress)
+	var buf bytes.Buffer
+	err := svgTpls[style].Execute(&buf, newSvgParams(style, frame))
+	if err != nil && templateHashes[style] != "" {
+		log.Printf("template %s: %v, using the built-in one", style, err)
+		buf.Reset()
+		err = svgTpls[StyleRing].Execute(&buf, newSvgParams(StyleRing, frame))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_ = os.MkdirAll(dirname, 0755)
+	err = cache.write(filename, buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("write SVG: %w", err)
+	}
+
+	return filename, nil
+}
+
+func newSvgParams(style string, frame Frame) svgParams {
 	radius := float64(width)/2 - float64(strokeWidth) - float64(padding)
-	baseWidth := int(math.Round(strokeWidth * 0.25))
 	circumference := 2 * math.Pi * radius
-	dashOffset := circumference * (1 - progress/100)
-
 	params := svgParams{
 		Width:         width,
 		Height:        height,
-		CenterX:       centerX,
-		CenterY:       centerY,
+		CenterX:       width / 2,
+		CenterY:       height / 2,
 		Radius:        radius,
-		BaseWidth:     baseWidth,
+		BaseWidth:     int(math.Round(strokeWidth * 0.25)),
 		StrokeWidth:   strokeWidth,
 		FgStrokeColor: Overrides.Color,
 		BgStrokeColor: bgStrokeColor,
 		Circumference: circumference,
-		DashOffset:    dashOffset,
+		DashOffset:    circumference * (1 - frame.Progress/100),
 		HasShadow:     Overrides.HasShadow,
 		Rounded:       Overrides.Rounded,
 		CustomOrigin:  roundedOrigin,
-		Progress:      int(progress),
-	}
-
-	var buf bytes.Buffer
-	err := svgTpl.Execute(&buf, params)
-	if err != nil {
-		return "", err
+		Progress:      int(frame.Progress),
+		Style:         style,
+		Text:          digitsText(frame.Left),
+		FontSize:      digitsSize,
+
+		Remaining:        FormatDuration(frame.Left),
+		RemainingSeconds: int(frame.Left.Round(time.Second) / time.Second),
+		Title:            html.EscapeString(frame.Title),
+		Phase:            html.EscapeString(frame.Phase),
+		Step:             frame.Step,
+		Steps:            frame.Steps,
 	}
 
-	_ = os.MkdirAll(dirname, 0755)
-	err = os.WriteFile(filename, buf.Bytes(), 0644)
-	if err != nil {
-		return "", fmt.Errorf("write SVG: %w", err)
-	}
-
-	cacheMu.Lock()
-	if cacheLoaded {
-		cache[filename] = struct{}{}
+	switch style {
+	case StylePie:
+		p := newPie(frame.Progress)
+		params.Radius = p.radius
+		if p.sweep >= 2*math.Pi {
+			// an arc can't end where it starts
+			params.Path = arcPath(p.radius, p.origin, math.Pi) + arcPath(p.radius, p.origin+math.Pi, math.Pi)
+		} else if p.sweep > 0 {
+			params.Path = fmt.Sprintf("M %d %d L", width/2, height/2) + strings.TrimPrefix(arcPath(p.radius, p.origin, p.sweep), "M") + " Z"
+		}
+	case StyleBar:
+		b := newBar(frame.Progress)
+		params.BarX = (float64(width) - barLength) / 2
+		params.BarY = (float64(height) - b.height) / 2
+		params.BarLength = barLength
+		params.BarFill = b.fill
+		params.Corners = b.corners
+		params.FillCorners = min(b.corners, b.fill/2)
+	case StyleSegments:
+		s := newSegments(frame)
+		for _, arc := range s.total {
+			params.Path += arcPath(arc.radius, arc.origin, arc.sweep)
+		}
+		for _, arc := range s.done {
+			params.DonePath += arcPath(arc.radius, arc.origin, arc.sweep)
+		}
+	case StyleDigits:
+		params.TextPath = digitsPath(params.Text, float64(width)/2, digitsSize)
 	}
-	cacheMu.Unlock()
 
-	return filename, nil
+	return params
 }
 
-func Pngify(filename string) ([]byte, error) {
-	pngFilename := filename + ".png"
-
-	pngCacheMu.RLock()
-	if pngCacheLoaded {
-		_, exists := pngCache[pngFilename]
-		if exists {
-			pngCacheMu.RUnlock()
-			out, err := os.ReadFile(pngFilename)
-			if err != nil {
-				return nil, err
-			}
-			return out, nil
-		}
+// fileProgress is the progress the file of the style is drawn at. The digits and the templates
+// change every second, they are drawn in whole percents, so a timer writes a file a second rather than a file a frame.
+func fileProgress(style string, progress float64) float64 {
+	progress = math.Max(0, math.Min(100, progress))
+	if style == StyleDigits || templateHashes[style] != "" {
+		return math.Round(progress)
 	}
-	pngCacheMu.RUnlock()
 
-	in, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = in.Close() }()
+	return progress
+}
 
-	icon, err := oksvg.ReadIconStream(in)
-	if err != nil {
-		return nil, err
+// frameFootprint tells apart the frames that look different in the style, at 0.01% steps of progress
+func frameFootprint(style string, frame Frame) string {
+	progress := fileProgress(style, frame.Progress)
+	footprint := fmt.Sprintf("sh%v.r%v.%.2f", bool2int(Overrides.HasShadow), bool2int(Overrides.Rounded), progress)
+	switch style {
+	case StyleRing:
+	case StyleSegments:
+		count, _ := segmentCount(frame.Duration)
+		footprint = fmt.Sprintf("%s.%s.n%d", style, footprint, count)
+	case StyleDigits:
+		footprint = fmt.Sprintf("%s.%s.t%d", style, footprint, int64(frame.Left.Round(time.Second)/time.Second))
+	case StylePie, StyleBar:
+		footprint = style + "." + footprint
+	default:
+		// a template may show anything of the frame, and the same style may be edited later
+		footprint = fmt.Sprintf("%s.%s.%s.t%d.%s", style, templateHashes[style], footprint,
+			int64(frame.Left.Round(time.Second)/time.Second), frameHash(frame))
 	}
 
-	icon.SetTarget(0, 0, float64(width), float64(width))
-	fullImage := image.NewRGBA(image.Rect(0, 0, width, width))
-	icon.Draw(rasterx.NewDasher(width, width, rasterx.NewScannerGV(width, width, fullImage, fullImage.Bounds())), 1)
-
-	center := width / 2
-	cropTo := 96
-	halfCrop := cropTo / 2
-
-	cropRect := image.Rect(center-halfCrop, center-halfCrop, center+halfCrop, center+halfCrop)
-	croppedImage := fullImage.SubImage(cropRect).(*image.RGBA)
-
-	// ToDo I don't even know why it's mirrored bruh
-	mirroredImage := image.NewRGBA(image.Rect(0, 0, cropTo, cropTo))
-	for x := 0; x < cropTo; x++ {
-		for y := 0; y < cropTo; y++ {
-			mirroredImage.Set(cropTo-x-1, y, croppedImage.At(croppedImage.Bounds().Min.X+x, croppedImage.Bounds().Min.Y+y))
-		}
-	}
+	return footprint
+}
 
-	out := bytes.Buffer{}
-	err = png.Encode(&out, mirroredImage)
-	if err != nil {
-		return nil, err
-	}
+// FrameKey is the same for the frames that render the same, e.g. to skip encoding an unchanged image
+func FrameKey(frame Frame) string {
+	return Overrides.Color + "/" + frameFootprint(Overrides.Style, frame)
+}
 
-	go func() {
-		err := os.WriteFile(pngFilename, out.Bytes(), 0644)
-		if err != nil {
-			log.Printf("writing PNG cache: %v", err)
-		}
+// frameHash tells apart the texts of the frame in a filename
+func frameHash(frame Frame) string {
+	h := fnv.New32a()
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d", frame.Title, frame.Phase, frame.Step, frame.Steps)
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
 
-		pngCacheMu.Lock()
-		if pngCacheLoaded {
-			pngCache[pngFilename] = struct{}{}
-		}
-		pngCacheMu.Unlock()
-	}()
+// arcPath is the path data of a clockwise arc around the center of the image
+func arcPath(radius float64, start float64, sweep float64) string {
+	cx, cy := float64(width)/2, float64(height)/2
+	end := start + sweep
+	return fmt.Sprintf("M %.2f %.2f A %.2f %.2f 0 %d 1 %.2f %.2f ",
+		cx+radius*math.Cos(start), cy+radius*math.Sin(start),
+		radius, radius, bool2int(sweep > math.Pi),
+		cx+radius*math.Cos(end), cy+radius*math.Sin(end),
+	)
+}
 
-	return out.Bytes(), nil
+// FlushCache waits for the evictions started by the cache writes
+func FlushCache() {
+	evictions.Wait()
 }
 
 func walk(filename string) {
@@ -189,16 +196,10 @@ func walk(filename string) {
 		if info.IsDir() {
 			walk(path)
 		} else {
-			ext := filepath.Ext(info.Name())
-			switch ext {
-			case ".svg":
-				cacheMu.Lock()
-				cache[path] = struct{}{}
-				cacheMu.Unlock()
-			case ".png":
-				pngCacheMu.Lock()
-				pngCache[path] = struct{}{}
-				pngCacheMu.Unlock()
+			// the PNG files of the former tray icon are evicted along
+			switch filepath.Ext(info.Name()) {
+			case ".svg", ".png":
+				cache.adopt(path)
 			}
 		}
 
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"log"
 	"math"
 	"regexp"
+	"time"
 )
 
 type Prefs struct {
@@ -20,12 +22,31 @@ type Prefs struct {
 	DefaultTitle       string
 	DefaultText        string
 	SoundFilename      string
+	SoundName          string
+	AudioSink          string
+	FadeSeconds        uint
+	FadeFrom           float64
 	ActivatePreset     bool
 	RememberWinSize    bool
 	ForceTrayIcon      bool
+	CountSuspend       bool
+	RestoreTimers      bool
+	SnoozeMinutes      uint
+	Warnings           string
+	Tick               bool
+	Chime              bool
+	Countdown          uint
+	TickVolume         float64
+	ChimeVolume        float64
+	CountdownVolume    float64
+	RingUntilDismissed bool
+	Crescendo          bool
+	MaxRingMinutes     uint
 	Shadow             bool
 	Rounded            bool
+	Style              string
 	LowFPS             bool
+	CacheSizeMB        uint
 	StartPresetOnClick bool
 	ShowTitle          bool
 	WindowWidth        uint
@@ -54,12 +75,31 @@ func LoadPrefs() {
 		DefaultTitle:       settings.String("default-title"),
 		DefaultText:        settings.String("default-text"),
 		SoundFilename:      settings.String("sound-filename"),
+		SoundName:          settings.String("sound-name"),
+		AudioSink:          settings.String("audio-sink"),
+		FadeSeconds:        settings.Uint("fade-seconds"),
+		FadeFrom:           settings.Double("fade-from"),
 		ActivatePreset:     settings.Boolean("activate-preset"),
 		RememberWinSize:    settings.Boolean("remember-window-size"),
 		Shadow:             settings.Boolean("shadow"),
 		Rounded:            settings.Boolean("rounded"),
+		Style:              settings.String("style"),
 		LowFPS:             settings.Boolean("low-fps"),
+		CacheSizeMB:        settings.Uint("cache-size-mb"),
 		ForceTrayIcon:      settings.Boolean("force-tray-icon"),
+		CountSuspend:       settings.Boolean("count-suspend"),
+		RestoreTimers:      settings.Boolean("restore-timers"),
+		SnoozeMinutes:      settings.Uint("snooze-minutes"),
+		Warnings:           settings.String("warnings"),
+		Tick:               settings.Boolean("tick"),
+		Chime:              settings.Boolean("chime"),
+		Countdown:          settings.Uint("countdown"),
+		TickVolume:         settings.Double("tick-volume"),
+		ChimeVolume:        settings.Double("chime-volume"),
+		CountdownVolume:    settings.Double("countdown-volume"),
+		RingUntilDismissed: settings.Boolean("ring-until-dismissed"),
+		Crescendo:          settings.Boolean("crescendo"),
+		MaxRingMinutes:     settings.Uint("max-ring-minutes"),
 		ShowTitle:          settings.Boolean("show-title"),
 		StartPresetOnClick: settings.Boolean("start-preset-on-click"),
 		WindowWidth:        settings.Uint("window-width"),
@@ -135,6 +175,12 @@ func SetRounded(value bool) {
 	settings.SetBoolean("rounded", value)
 }
 
+func SetStyle(value string) {
+	Overrides.Style = value
+	UserPrefs.Style = value
+	settings.SetString("style", value)
+}
+
 func SetShowTitle(value bool) {
 	UserPrefs.ShowTitle = value
 	settings.SetBoolean("show-title", value)
@@ -146,12 +192,101 @@ func SetLowFPS(value bool) {
 	settings.SetBoolean("low-fps", value)
 }
 
+func SetCacheSizeMB(value uint) {
+	Overrides.CacheSize = value
+	UserPrefs.CacheSizeMB = value
+	settings.SetUint("cache-size-mb", value)
+	go EvictCache()
+}
+
 func SetForceTrayIcon(value bool) {
 	Overrides.ForceTrayIcon = value
 	UserPrefs.ForceTrayIcon = value
 	settings.SetBoolean("force-tray-icon", value)
 }
 
+func SetCountSuspend(value bool) {
+	Overrides.CountSuspend = value
+	UserPrefs.CountSuspend = value
+	settings.SetBoolean("count-suspend", value)
+}
+
+func SetRestoreTimers(value bool) {
+	UserPrefs.RestoreTimers = value
+	settings.SetBoolean("restore-timers", value)
+}
+
+func SetSnoozeMinutes(value uint) {
+	Overrides.Snooze = time.Duration(value) * time.Minute
+	UserPrefs.SnoozeMinutes = value
+	settings.SetUint("snooze-minutes", value)
+}
+
+func SetWarnings(value string) {
+	warnings, err := ParseWarnings(value)
+	if err != nil {
+		return
+	}
+
+	Overrides.Warnings = warnings
+	UserPrefs.Warnings = value
+	settings.SetString("warnings", value)
+}
+
+func SetTick(value bool) {
+	Overrides.Tick = value
+	UserPrefs.Tick = value
+	settings.SetBoolean("tick", value)
+}
+
+func SetChime(value bool) {
+	Overrides.Chime = value
+	UserPrefs.Chime = value
+	settings.SetBoolean("chime", value)
+}
+
+func SetCountdown(value uint) {
+	Overrides.Countdown = int(value)
+	UserPrefs.Countdown = value
+	settings.SetUint("countdown", value)
+}
+
+func SetTickVolume(value float64) {
+	Overrides.TickVolume = value
+	UserPrefs.TickVolume = value
+	settings.SetDouble("tick-volume", value)
+}
+
+func SetChimeVolume(value float64) {
+	Overrides.ChimeVolume = value
+	UserPrefs.ChimeVolume = value
+	settings.SetDouble("chime-volume", value)
+}
+
+func SetCountdownVolume(value float64) {
+	Overrides.CountdownVolume = value
+	UserPrefs.CountdownVolume = value
+	settings.SetDouble("countdown-volume", value)
+}
+
+func SetRingUntilDismissed(value bool) {
+	Overrides.Ring = value
+	UserPrefs.RingUntilDismissed = value
+	settings.SetBoolean("ring-until-dismissed", value)
+}
+
+func SetCrescendo(value bool) {
+	Overrides.Crescendo = value
+	UserPrefs.Crescendo = value
+	settings.SetBoolean("crescendo", value)
+}
+
+func SetMaxRingMinutes(value uint) {
+	Overrides.MaxRing = time.Duration(value) * time.Minute
+	UserPrefs.MaxRingMinutes = value
+	settings.SetUint("max-ring-minutes", value)
+}
+
 func SetProgressColor(value string) {
 	if !regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`).MatchString(value) {
 		return
@@ -185,10 +320,41 @@ func SetSoundFilename(value string) {
 
 	err := LoadSound()
 	if err != nil {
+		log.Printf("sound file: %v", err)
 		SetSoundFilename("")
 	}
 }
 
+func SetSoundName(value string) {
+	Overrides.SoundName = value
+	UserPrefs.SoundName = value
+	settings.SetString("sound-name", value)
+
+	err := LoadSound()
+	if err != nil {
+		log.Printf("sound name: %v", err)
+		SetSoundName("")
+	}
+}
+
+// SetAudioSink takes effect on the next launch, the audio context lives as long as the process
+func SetAudioSink(value string) {
+	UserPrefs.AudioSink = value
+	settings.SetString("audio-sink", value)
+}
+
+func SetFadeSeconds(value uint) {
+	Overrides.Fade = time.Duration(value) * time.Second
+	UserPrefs.FadeSeconds = value
+	settings.SetUint("fade-seconds", value)
+}
+
+func SetFadeFrom(value float64) {
+	Overrides.FadeFrom = value
+	UserPrefs.FadeFrom = value
+	settings.SetDouble("fade-from", value)
+}
+
 func SetDefaultText(value string) {
 	Overrides.Text = value
 	UserPrefs.DefaultText = value
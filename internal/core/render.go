@@ -0,0 +1,381 @@
+package core
+
+import (
+	"bytes"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// trayCrop is the part of the artwork shown in the tray, the padding is cut off
+	trayCrop = 96
+
+	// fallbackColor is the GNOME blue, used until the accent color is known
+	fallbackColor = "#3584E4"
+
+	shadowOffsetX = -4
+	shadowOffsetY = 7
+	shadowSigma   = 3 // the blur radius of the drop-shadow is 6px
+	shadowAlpha   = 0.2
+	shadowGray    = 16
+)
+
+// ring is the progress circle of svgTemplate, in the coordinates of the SVG centered at the origin
+type ring struct {
+	radius    float64
+	baseWidth float64
+	width     float64
+	origin    float64 // radians, clockwise from 3 o'clock
+	sweep     float64
+	rounded   bool
+	ends      [2][2]float64 // the directions of both ends of the arc
+}
+
+func newRing(progress float64) ring {
+	origin := -90.0
+	if Overrides.Rounded {
+		origin = roundedOrigin
+	}
+
+	r := ring{
+		radius:    float64(width)/2 - float64(strokeWidth) - float64(padding),
+		baseWidth: math.Round(strokeWidth * 0.25),
+		width:     strokeWidth,
+		sweep:     2 * math.Pi * max(0, min(100, progress)) / 100,
+		rounded:   Overrides.Rounded,
+	}
+
+	r.rotate(origin)
+	return r
+}
+
+// rotate moves the start of the arc, in degrees
+func (r *ring) rotate(origin float64) {
+	r.origin = origin * math.Pi / 180
+	for i, angle := range []float64{r.origin, r.origin + r.sweep} {
+		r.ends[i] = [2]float64{math.Cos(angle), math.Sin(angle)}
+	}
+}
+
+// RenderProgress draws the same artwork as MakeProgressCircle, without the SVG round trip,
+// scaled to a square of the given size
+func RenderProgress(frame Frame, size int) *image.RGBA {
+	return renderFrame(Overrides.Style, frame, size, 0)
+}
+
+// RenderTrayIcon is the artwork without the padding, as shown in the tray
+func RenderTrayIcon(frame Frame, size int) *image.RGBA {
+	return renderFrame(Overrides.Style, frame, size, (width-trayCrop)/2)
+}
+
+// ProgressPNG is RenderProgress encoded as PNG
+func ProgressPNG(frame Frame, size int) ([]byte, error) {
+	return encodePNG(RenderProgress(frame, size))
+}
+
+// TrayIconPNG is RenderTrayIcon encoded as PNG, at the size of the tray icon
+func TrayIconPNG(frame Frame) ([]byte, error) {
+	return encodePNG(RenderTrayIcon(frame, trayCrop))
+}
+
+// pngEncoder favors speed, the images are small and rendered many times a second
+var pngEncoder = png.Encoder{CompressionLevel: png.BestSpeed, BufferPool: &pngBuffers{}}
+
+type pngBuffers struct {
+	pool sync.Pool
+}
+
+func (b *pngBuffers) Get() *png.EncoderBuffer {
+	buf, _ := b.pool.Get().(*png.EncoderBuffer)
+	return buf
+}
+
+func (b *pngBuffers) Put(buf *png.EncoderBuffer) {
+	b.pool.Put(buf)
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	out := bytes.Buffer{}
+	if err := pngEncoder.Encode(&out, img); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// renderFrame rasterizes the style with analytic anti-aliasing,
+// crop is cut off every side of the SVG before scaling
+func renderFrame(style string, frame Frame, size int, crop float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	if size <= 0 {
+		return img
+	}
+
+	// only oksvg knows how a custom template looks, the ring is drawn if it fails
+	if templateHashes[style] != "" {
+		if rendered := renderTemplate(style, frame, size, crop); rendered != nil {
+			return rendered
+		}
+	}
+
+	bg := parseHexColor(bgStrokeColor)
+	fg := parseHexColor(Overrides.Color)
+	shadow := color.NRGBA{R: shadowGray, G: shadowGray, B: shadowGray, A: 255}
+	s := newShape(style, frame)
+
+	// SVG units per pixel, the coverage of an edge spans one pixel
+	unit := (float64(width) - 2*crop) / float64(size)
+	center := float64(width) / 2
+
+	for py := range size {
+		for px := range size {
+			x := crop + (float64(px)+0.5)*unit - center
+			y := crop + (float64(py)+0.5)*unit - center
+
+			var pixel [4]float64
+			blend(&pixel, bg, coverage(s.track(x, y), unit))
+
+			// the shadow is under the progress, nothing to see where it's opaque
+			progress := coverage(s.progress(x, y), unit)
+			if Overrides.HasShadow && progress < 1 {
+				blend(&pixel, shadow, shadowAlpha*blur(s.progress(x-shadowOffsetX, y-shadowOffsetY)))
+			}
+
+			blend(&pixel, fg, progress)
+			if pixel[3] > 0 {
+				setPixel(img, px, py, pixel)
+			}
+		}
+	}
+
+	if style == StyleDigits {
+		drawDigits(img, digitsText(frame.Left), fg, (center-crop)/unit, digitsSize/unit)
+	}
+
+	return img
+}
+
+// digitsFont is Go Mono Bold, so the digits don't jump around
+var digitsFont = sync.OnceValue(func() *opentype.Font {
+	f, err := opentype.Parse(gomonobold.TTF)
+	if err != nil {
+		log.Printf("parse digits font: %v", err)
+	}
+
+	return f
+})
+
+// drawDigits centers the text like text-anchor="middle" and dominant-baseline="central" in SVG
+func drawDigits(img *image.RGBA, text string, c color.NRGBA, center float64, size float64) {
+	f := digitsFont()
+	if f == nil {
+		return
+	}
+
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		log.Printf("digits font face: %v", err)
+		return
+	}
+	defer func() { _ = face.Close() }()
+
+	metrics := face.Metrics()
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+	origin := fixed.Int26_6(center * 64)
+	drawer.Dot = fixed.Point26_6{
+		X: origin - drawer.MeasureString(text)/2,
+		Y: origin + (metrics.Ascent-metrics.Descent)/2,
+	}
+	drawer.DrawString(text)
+}
+
+// digitsPath is the text as the outlines of the glyphs, laid out like drawDigits
+func digitsPath(text string, center float64, size float64) string {
+	f := digitsFont()
+	if f == nil {
+		return ""
+	}
+
+	var buf sfnt.Buffer
+	ppem := fixed.Int26_6(size * 64)
+	metrics, err := f.Metrics(&buf, ppem, font.HintingNone)
+	if err != nil {
+		log.Printf("digits font metrics: %v", err)
+		return ""
+	}
+
+	var glyphs []sfnt.GlyphIndex
+	var advances []fixed.Int26_6
+	var total fixed.Int26_6
+	for _, r := range text {
+		glyph, err := f.GlyphIndex(&buf, r)
+		if err != nil {
+			log.Printf("digits glyph %q: %v", r, err)
+			return ""
+		}
+
+		advance, err := f.GlyphAdvance(&buf, glyph, ppem, font.HintingNone)
+		if err != nil {
+			log.Printf("digits glyph %q: %v", r, err)
+			return ""
+		}
+
+		glyphs = append(glyphs, glyph)
+		advances = append(advances, advance)
+		total += advance
+	}
+
+	origin := fixed.Int26_6(center * 64)
+	dot := fixed.Point26_6{X: origin - total/2, Y: origin + (metrics.Ascent-metrics.Descent)/2}
+	coord := func(v fixed.Int26_6) string {
+		return strconv.FormatFloat(float64(v)/64, 'f', -1, 64)
+	}
+
+	var path strings.Builder
+	for i, glyph := range glyphs {
+		segments, err := f.LoadGlyph(&buf, glyph, ppem, nil)
+		if err != nil {
+			log.Printf("digits glyph %d: %v", glyph, err)
+			return ""
+		}
+
+		for _, segment := range segments {
+			args := 1
+			switch segment.Op {
+			case sfnt.SegmentOpMoveTo:
+				if path.Len() > 0 {
+					path.WriteString("Z ")
+				}
+				path.WriteString("M")
+			case sfnt.SegmentOpLineTo:
+				path.WriteString("L")
+			case sfnt.SegmentOpQuadTo:
+				path.WriteString("Q")
+				args = 2
+			case sfnt.SegmentOpCubeTo:
+				path.WriteString("C")
+				args = 3
+			}
+
+			for _, p := range segment.Args[:args] {
+				path.WriteString(" " + coord(dot.X+p.X) + " " + coord(dot.Y+p.Y) + " ")
+			}
+		}
+
+		dot.X += advances[i]
+	}
+
+	if path.Len() > 0 {
+		path.WriteString("Z")
+	}
+
+	return path.String()
+}
+
+// distance is the signed distance from the edge of the progress arc, negative inside
+func (r ring) distance(x, y float64) float64 {
+	// the caps are within the band of the circle, far from it is far from the arc
+	if band := math.Abs(length(x, y)-r.radius) - r.width/2; band > farDistance {
+		return band
+	}
+
+	if r.sweep >= 2*math.Pi {
+		return math.Abs(length(x, y)-r.radius) - r.width/2
+	}
+
+	if r.covers(x, y) {
+		return math.Abs(length(x, y)-r.radius) - r.width/2
+	}
+
+	return min(r.capDistance(x, y, r.ends[0]), r.capDistance(x, y, r.ends[1]))
+}
+
+// covers reports whether the direction of the point is within the arc,
+// cross products instead of angles, atan2 is too slow for every pixel
+func (r ring) covers(x, y float64) bool {
+	start, end := r.ends[0], r.ends[1]
+	afterStart := start[0]*y-start[1]*x >= 0
+	beforeEnd := x*end[1]-y*end[0] >= 0
+	if r.sweep <= math.Pi {
+		return afterStart && beforeEnd
+	}
+
+	return afterStart || beforeEnd
+}
+
+// capDistance is the distance from the end of the arc in the given direction,
+// a half circle for rounded caps and a radial line for butt caps
+func (r ring) capDistance(x, y float64, end [2]float64) float64 {
+	ux, uy := end[0], end[1]
+	if r.rounded {
+		return length(x-ux*r.radius, y-uy*r.radius) - r.width/2
+	}
+
+	along := max(r.radius-r.width/2, min(r.radius+r.width/2, x*ux+y*uy))
+	return length(x-ux*along, y-uy*along)
+}
+
+// length is math.Hypot without the overflow care, which takes most of the time otherwise
+func length(x, y float64) float64 {
+	return math.Sqrt(x*x + y*y)
+}
+
+// blur approximates the coverage of a gaussian blurred edge with a smoothstep
+func blur(distance float64) float64 {
+	t := max(0, min(1, 0.5-distance/(5*shadowSigma)))
+	return t * t * (3 - 2*t)
+}
+
+func coverage(distance float64, unit float64) float64 {
+	return max(0, min(1, 0.5-distance/unit))
+}
+
+// blend paints the color over the premultiplied pixel
+func blend(pixel *[4]float64, c color.NRGBA, alpha float64) {
+	if alpha <= 0 {
+		return
+	}
+
+	alpha *= float64(c.A) / 255
+	pixel[0] = float64(c.R)*alpha + pixel[0]*(1-alpha)
+	pixel[1] = float64(c.G)*alpha + pixel[1]*(1-alpha)
+	pixel[2] = float64(c.B)*alpha + pixel[2]*(1-alpha)
+	pixel[3] = 255*alpha + pixel[3]*(1-alpha)
+}
+
+func setPixel(img *image.RGBA, x, y int, pixel [4]float64) {
+	i := img.PixOffset(x, y)
+	for c := range pixel {
+		img.Pix[i+c] = uint8(math.Round(pixel[c]))
+	}
+}
+
+// parseHexColor understands #RGB and #RRGGBB
+func parseHexColor(value string) color.NRGBA {
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		if value != fallbackColor {
+			return parseHexColor(fallbackColor)
+		}
+
+		return color.NRGBA{A: 255}
+	}
+
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"bytes"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"image"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// svgTrayIcon is the former tray icon: the SVG file rasterized by oksvg, cropped and mirrored,
+// oksvg draws the arcs the other way around
+func svgTrayIcon(filename string) (*image.RGBA, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = in.Close() }()
+
+	icon, err := oksvg.ReadIconStream(in)
+	if err != nil {
+		return nil, err
+	}
+
+	icon.SetTarget(0, 0, float64(width), float64(height))
+	full := image.NewRGBA(image.Rect(0, 0, width, height))
+	icon.Draw(rasterx.NewDasher(width, height, rasterx.NewScannerGV(width, height, full, full.Bounds())), 1)
+
+	offset := (width - trayCrop) / 2
+	img := image.NewRGBA(image.Rect(0, 0, trayCrop, trayCrop))
+	for y := range trayCrop {
+		for x := range trayCrop {
+			img.Set(trayCrop-x-1, y, full.At(offset+x, offset+y))
+		}
+	}
+
+	return img, nil
+}
+
+// TestRenderTrayIcon compares the native tray icon with the former SVG path
+func TestRenderTrayIcon(t *testing.T) {
+	CacheDir = t.TempDir()
+	Overrides.Color = fallbackColor
+	Overrides.Style = StyleRing
+	defer FlushCache()
+
+	for _, shadow := range []bool{false, true} {
+		for _, rounded := range []bool{false, true} {
+			for _, progress := range []float64{0, 12.5, 50, 87.3, 100} {
+				Overrides.HasShadow = shadow
+				frame := Frame{Progress: progress, Left: time.Minute, Duration: 2 * time.Minute}
+
+				// oksvg draws the round cap of the empty arc as a dot, the native icon shows
+				// no progress at all there, like the empty arc without the caps
+				Overrides.Rounded = rounded && progress > 0
+				filename, err := MakeProgressCircle(frame)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected, err := svgTrayIcon(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// the edges are anti-aliased differently, the shapes must be the same
+				Overrides.Rounded = rounded
+				mean, off := imageDiff(RenderTrayIcon(frame, trayCrop), expected, trayCrop)
+				if mean > 10 || off > trayCrop*trayCrop/50 {
+					t.Errorf("shadow %v, rounded %v, progress %v: mean difference %.1f, %d pixels off", shadow, rounded, progress, mean, off)
+				}
+			}
+		}
+	}
+}
+
+// TestDigitsFont compares the digits of the SVG, as any renderer draws them, with the native ones
+func TestDigitsFont(t *testing.T) {
+	Overrides.Color = fallbackColor
+	Overrides.HasShadow = false
+	Overrides.Rounded = false
+
+	for _, left := range []time.Duration{0, 59 * time.Second, 10*time.Minute + 7*time.Second, 2*time.Hour + 30*time.Minute} {
+		// oksvg draws the arc the other way around, a full one is the same
+		frame := Frame{Progress: 100, Left: left, Duration: 3 * time.Hour}
+		var buf bytes.Buffer
+		if err := svgTpls[StyleDigits].Execute(&buf, newSvgParams(StyleDigits, frame)); err != nil {
+			t.Fatal(err)
+		}
+
+		icon, err := oksvg.ReadIconStream(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		icon.SetTarget(0, 0, float64(width), float64(height))
+		expected := image.NewRGBA(image.Rect(0, 0, width, height))
+		icon.Draw(rasterx.NewDasher(width, height, rasterx.NewScannerGV(width, height, expected, expected.Bounds())), 1)
+
+		mean, off := imageDiff(renderFrame(StyleDigits, frame, width, 0), expected, width)
+		if mean > 10 || off > width*height/50 {
+			t.Errorf("%s: mean difference %.1f, %d pixels off", digitsText(left), mean, off)
+		}
+	}
+}
+
+// imageDiff is the mean of the largest channel difference of the pixels, and the number of those far off
+func imageDiff(img image.Image, expected image.Image, size int) (float64, int) {
+	total, off := 0.0, 0
+	for y := range size {
+		for x := range size {
+			r1, g1, b1, a1 := img.At(x, y).RGBA()
+			r2, g2, b2, a2 := expected.At(x, y).RGBA()
+			diff := 0.0
+			for _, pair := range [][2]uint32{{r1, r2}, {g1, g2}, {b1, b2}, {a1, a2}} {
+				diff = max(diff, math.Abs(float64(pair[0]>>8)-float64(pair[1]>>8)))
+			}
+
+			total += diff
+			if diff > 64 {
+				off++
+			}
+		}
+	}
+
+	return total / float64(size*size), off
+}
+
+func benchmarkSetup(b *testing.B) {
+	CacheDir = b.TempDir()
+	Overrides.Color = "#3584E4"
+	Overrides.HasShadow = true
+	Overrides.Rounded = true
+	Overrides.Style = StyleRing
+	b.ReportAllocs()
+	b.ResetTimer()
+}
+
+// benchmarkFrame walks through the 0.01% steps like a running 25 minutes timer
+func benchmarkFrame(i int) Frame {
+	progress := float64(i%10000) / 100
+	duration := 25 * time.Minute
+	return Frame{
+		Progress: progress,
+		Left:     duration - time.Duration(float64(duration)*progress/100),
+		Duration: duration,
+	}
+}
+
+// BenchmarkTrayIconSVG is the former tray path: an SVG file rasterized by oksvg
+func BenchmarkTrayIconSVG(b *testing.B) {
+	benchmarkSetup(b)
+	for i := range b.N {
+		filename, err := MakeProgressCircle(benchmarkFrame(i))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		img, err := svgTrayIcon(filename)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err = encodePNG(img); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	FlushCache()
+}
+
+func BenchmarkTrayIconPNG(b *testing.B) {
+	benchmarkSetup(b)
+	for i := range b.N {
+		if _, err := TrayIconPNG(benchmarkFrame(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderProgress128(b *testing.B) {
+	benchmarkSetup(b)
+	for i := range b.N {
+		RenderProgress(benchmarkFrame(i), 128)
+	}
+}
+
+func BenchmarkRenderProgress512(b *testing.B) {
+	benchmarkSetup(b)
+	for i := range b.N {
+		RenderProgress(benchmarkFrame(i), 512)
+	}
+}
+
+func BenchmarkTrayIconStyles(b *testing.B) {
+	for _, style := range Styles {
+		b.Run(style, func(b *testing.B) {
+			benchmarkSetup(b)
+			Overrides.Style = style
+			for i := range b.N {
+				if _, err := TrayIconPNG(benchmarkFrame(i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
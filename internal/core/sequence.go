@@ -0,0 +1,202 @@
+package core
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRepeat = 100
+
+// Segment is a single phase of a sequence, e.g. "25m work"
+type Segment struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// ParseSequence understands comma separated steps like "25m work, 5m break ×4, 15m long break".
+// A trailing "×N" (or "xN", "*N") repeats all the steps since the previous repeated one.
+// So a name can't contain a comma nor end with such a word, and a step must have a unit:
+// "25 work" is rejected rather than taken as 25 seconds.
+func ParseSequence(value string) ([]Segment, error) {
+	var segments []Segment
+	groupStart := 0
+	for _, step := range strings.Split(value, ",") {
+		fields := strings.Fields(step)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("sequence %q: empty step", value)
+		}
+
+		repeat, hasRepeat := parseRepeat(fields[len(fields)-1])
+		if hasRepeat {
+			fields = fields[:len(fields)-1]
+		}
+
+		segment, err := parseSegment(fields)
+		if err != nil {
+			return nil, fmt.Errorf("sequence %q: %w", value, err)
+		}
+
+		segments = append(segments, segment)
+		if !hasRepeat {
+			continue
+		}
+
+		if repeat < 1 || repeat > maxRepeat {
+			return nil, fmt.Errorf("sequence %q: can repeat 1 to %d times", value, maxRepeat)
+		}
+
+		group := slices.Clone(segments[groupStart:])
+		for range repeat - 1 {
+			segments = append(segments, group...)
+		}
+		groupStart = len(segments)
+	}
+
+	return segments, nil
+}
+
+// parseRepeat recognizes "×4", "x4" and "*4"
+func parseRepeat(field string) (int, bool) {
+	for _, prefix := range []string{"×", "x", "X", "*"} {
+		if num, ok := strings.CutPrefix(field, prefix); ok {
+			repeat, err := strconv.Atoi(num)
+			return repeat, err == nil
+		}
+	}
+
+	return 0, false
+}
+
+// parseSegment takes the longest leading duration, the rest is the name
+func parseSegment(fields []string) (Segment, error) {
+	if len(fields) == 0 {
+		return Segment{}, fmt.Errorf("step without a duration")
+	}
+
+	step := strings.Join(fields, " ")
+	for i := len(fields); i > 0; i-- {
+		d, err := ParseDuration(strings.Join(fields[:i], " "))
+		if err != nil {
+			continue
+		}
+
+		if d < time.Second {
+			return Segment{}, fmt.Errorf("step %q: must be at least 1 second", step)
+		}
+
+		if _, err = strconv.Atoi(fields[0]); i == 1 && err == nil {
+			return Segment{}, fmt.Errorf("step %q: add a unit, e.g. %sm or %ss", step, fields[0], fields[0])
+		}
+
+		return Segment{Name: strings.Join(fields[i:], " "), Duration: d.Round(time.Second)}, nil
+	}
+
+	return Segment{}, fmt.Errorf("step %q: expected a duration first, e.g. \"25m work\"", step)
+}
+
+// FormatSequence is the reverse of ParseSequence, with all the repeats expanded
+func FormatSequence(segments []Segment) string {
+	steps := make([]string, len(segments))
+	for i, segment := range segments {
+		steps[i] = strings.TrimSpace(formatUnits(segment.Duration) + " " + segment.Name)
+	}
+
+	return strings.Join(steps, ", ")
+}
+
+// formatUnits writes a duration the way a step has it, e.g. "1h30m" or "45s"
+func formatUnits(d time.Duration) string {
+	var b strings.Builder
+	d = d.Round(time.Second)
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}} {
+		if n := d / unit.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.suffix)
+			d -= n * unit.size
+		}
+	}
+
+	return b.String()
+}
+
+// TotalDuration is the sum of all the segments
+func TotalDuration(segments []Segment) time.Duration {
+	var total time.Duration
+	for _, segment := range segments {
+		total += segment.Duration
+	}
+
+	return total
+}
+
+// NewSequencePlayer creates a timer that runs the segments one by one
+func NewSequencePlayer(segments []Segment, name string) (*TimerPlayer, error) {
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty sequence")
+	}
+
+	p, err := NewTimerPlayer(int(segments[0].Duration/time.Second), name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Options.Sequence = segments
+	return p, nil
+}
+
+func (p *TimerPlayer) isSequence() bool {
+	return len(p.Options.Sequence) > 0
+}
+
+func (p *TimerPlayer) hasNextPhase() bool {
+	return p.phase+1 < len(p.Options.Sequence)
+}
+
+// Phase is the current segment, a plain timer is a single segment
+func (p *TimerPlayer) Phase() Segment {
+	if !p.isSequence() {
+		return Segment{Name: p.Name, Duration: p.duration}
+	}
+
+	return p.Options.Sequence[p.phase]
+}
+
+// nextPhase starts the next segment at the given time,
+// i.e. when the previous one ended or right now when skipped
+func (p *TimerPlayer) nextPhase(at time.Time) {
+	p.phase++
+	p.duration = p.Options.Sequence[p.phase].Duration
+	p.startTime = at
+	p.pausedFor = 0
+	if p.isPaused {
+		p.pausedAt = p.now()
+	}
+
+	p.armWarnings()
+	log.Printf("timer %s: phase %d/%d", p.Id, p.phase+1, len(p.Options.Sequence))
+	p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
+		"Metadata": dbus.MakeVariant(p.metadata(FormatDuration(p.duration-p.elapsed()), p.img)),
+	})
+	p.emitControl("PhaseStarted", p.Id, p.Phase().Name, uint32(p.phase))
+	p.save()
+}
+
+// PhaseTitle is shown as xesam:title of a sequence, the timer name goes to xesam:album then
+func (p *TimerPlayer) PhaseTitle() string {
+	if name := p.Phase().Name; name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("Step %d", p.phase+1)
+}
+
+func (p *TimerPlayer) phaseAlbum() string {
+	return fmt.Sprintf("%s · %d/%d", p.Name, p.phase+1, len(p.Options.Sequence))
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseSequence(t *testing.T) {
+	tests := []struct {
+		value     string
+		formatted string
+		fails     bool
+	}{
+		{value: "25m work, 5m break", formatted: "25m work, 5m break"},
+		{value: "25m work, 5m break ×2, 15m long break", formatted: "25m work, 5m break, 25m work, 5m break, 15m long break"},
+		{value: "1m a x2, 2m b *2", formatted: "1m a, 1m a, 2m b, 2m b"},
+		{value: "1m a, 2m b X2, 3m c ×2", formatted: "1m a, 2m b, 1m a, 2m b, 3m c, 3m c"},
+		{value: "1h 30 min deep work", formatted: "1h30m deep work"},
+		{value: "90s, 1:30:00 long one", formatted: "1m30s, 1h30m long one"},
+		{value: "25m xbox, 5m x", formatted: "25m xbox, 5m x"},
+		{value: "10m, 5m ×3", formatted: "10m, 5m, 10m, 5m, 10m, 5m"},
+		{value: "25 work", fails: true},
+		{value: "25m work, 5 break", fails: true},
+		{value: "25m work,", fails: true},
+		{value: "work 25m", fails: true},
+		{value: "×4", fails: true},
+		{value: "5m ×0", fails: true},
+		{value: "5m ×101", fails: true},
+		{value: "0s nothing", fails: true},
+	}
+
+	for _, test := range tests {
+		segments, err := ParseSequence(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseSequence(%q) = %v, expected an error", test.value, segments)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseSequence(%q): %v", test.value, err)
+			continue
+		}
+
+		formatted := FormatSequence(segments)
+		if formatted != test.formatted {
+			t.Errorf("FormatSequence(ParseSequence(%q)) = %q, expected %q", test.value, formatted, test.formatted)
+		}
+
+		again, err := ParseSequence(formatted)
+		if err != nil || !slices.Equal(again, segments) {
+			t.Errorf("ParseSequence(%q) = %v, %v, expected %v", formatted, again, err, segments)
+		}
+	}
+}
+
+func TestFormatSequence(t *testing.T) {
+	segments := []Segment{
+		{Name: "work", Duration: 25 * time.Minute},
+		{Duration: 90 * time.Second},
+		{Name: "long break", Duration: time.Hour + 5*time.Second},
+	}
+
+	formatted := FormatSequence(segments)
+	if formatted != "25m work, 1m30s, 1h5s long break" {
+		t.Errorf("FormatSequence() = %q", formatted)
+	}
+
+	parsed, err := ParseSequence(formatted)
+	if err != nil || !slices.Equal(parsed, segments) {
+		t.Errorf("ParseSequence(%q) = %v, %v, expected %v", formatted, parsed, err, segments)
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"github.com/godbus/dbus/v5"
+	"log"
+	"sync"
+)
+
+var (
+	sleepOnce        sync.Once
+	sleepMu          sync.Mutex
+	sleepSubscribers = make(map[string]chan<- bool)
+)
+
+// subscribeSleep delivers logind PrepareForSleep, true right before a suspend and false after resume
+func subscribeSleep(id string, ch chan<- bool) {
+	sleepOnce.Do(func() { go watchSleep() })
+
+	sleepMu.Lock()
+	sleepSubscribers[id] = ch
+	sleepMu.Unlock()
+}
+
+func unsubscribeSleep(id string) {
+	sleepMu.Lock()
+	delete(sleepSubscribers, id)
+	sleepMu.Unlock()
+}
+
+func watchSleep() {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		log.Printf("connect to system bus, suspend won't be tracked: %v", err)
+		return
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath("/org/freedesktop/login1"),
+		dbus.WithMatchInterface("org.freedesktop.login1.Manager"),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		log.Printf("subscribe to PrepareForSleep, suspend won't be tracked: %v", err)
+		return
+	}
+
+	signals := make(chan *dbus.Signal, 4)
+	conn.Signal(signals)
+	for signal := range signals {
+		if signal.Name != "org.freedesktop.login1.Manager.PrepareForSleep" || len(signal.Body) != 1 {
+			continue
+		}
+
+		sleeping, ok := signal.Body[0].(bool)
+		if !ok {
+			continue
+		}
+
+		log.Printf("PrepareForSleep: %v", sleeping)
+		sleepMu.Lock()
+		for _, ch := range sleepSubscribers {
+			select {
+			case ch <- sleeping:
+			default:
+			}
+		}
+		sleepMu.Unlock()
+	}
+}
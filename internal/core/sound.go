@@ -1,61 +1,282 @@
 package core
 
 import (
-	"bytes"
 	_ "embed"
-	"github.com/hajimehoshi/go-mp3"
+	"errors"
+	"fmt"
 	"github.com/hajimehoshi/oto/v2"
 	"io"
 	"log"
+	"math"
 	"os"
+	"os/exec"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// every sound is resampled to the rate of the single audio context
+	audioRate = 44100
+
+	ringPause     = time.Second
+	crescendoTime = time.Minute
+	crescendoFrom = 0.2
+)
+
 //go:embed res/ding.mp3
 var defaultSound []byte
 var sound []byte
 
+// soundDisabled means the sound theme wants silence instead of the sound
+var soundDisabled bool
+
+var (
+	audioCtx  *oto.Context
+	audioMu   sync.Mutex
+	ringing   = map[string]*TimerPlayer{}
+	ringingMu sync.Mutex
+)
+
+// LoadSound prefers the custom file, then the sound theme name, then the embedded sound.
+// The embedded sound is used if the chosen one fails.
 func LoadSound() error {
-	if Overrides.SoundFilename == "" {
+	soundDisabled = false
+	filename := Overrides.SoundFilename
+	if filename == "" && Overrides.SoundName != "" {
+		log.Printf("theme sound requested: %s", Overrides.SoundName)
+		path, err := ResolveSoundName(Overrides.SoundName)
+		if errors.Is(err, ErrSoundDisabled) {
+			log.Printf("theme sound: %v, staying silent", err)
+			soundDisabled = true
+			return nil
+		}
+
+		if err != nil {
+			sound = defaultSound
+			return err
+		}
+
+		filename = path
+	}
+
+	if filename == "" {
 		log.Println("default sound init requested")
 		sound = defaultSound
 		return nil
 	}
 
-	log.Printf("custom sound requested: %s", Overrides.SoundFilename)
-	file, err := os.Open(Overrides.SoundFilename)
+	log.Printf("custom sound requested: %s", filename)
+	file, err := os.Open(filename)
 	if err != nil {
+		sound = defaultSound
 		return err
 	}
+	defer func() { _ = file.Close() }()
 
-	sound, err = io.ReadAll(file)
+	data, err := io.ReadAll(file)
 	if err != nil {
+		sound = defaultSound
 		return err
 	}
 
+	if _, err = newDecoder(data); err != nil {
+		sound = defaultSound
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	sound = data
 	return nil
 }
 
+// PlaySound plays the sound once, fading in as configured
 func PlaySound() error {
-	dec, err := mp3.NewDecoder(bytes.NewReader(sound))
+	_, err := playSound(nil, fadeIn(time.Now(), Overrides.Fade, Overrides.FadeFrom))
+	return err
+}
+
+// fadeIn ramps the volume from the given one up to Overrides.Volume, counting from started
+func fadeIn(started time.Time, ramp time.Duration, from float64) func() float64 {
+	return func() float64 {
+		if ramp <= 0 || from >= Overrides.Volume {
+			return Overrides.Volume
+		}
+
+		progress := math.Min(1, float64(time.Since(started))/float64(ramp))
+		return from + (Overrides.Volume-from)*progress
+	}
+}
+
+// PlayWarningSound is a short double beep, unlike the alarm sound
+func PlayWarningSound() error {
+	_, err := play(beep(warningTone, warningPulse, warningPulse, 2), nil, func() float64 { return Overrides.Volume })
+	return err
+}
+
+// Ring plays the sound in a loop until the timer is silenced, for Overrides.MaxRing at most.
+// The fade goes once across all the repeats, Overrides.Crescendo stretches it to a minute at least
+// and starts it quiet unless the fade has its own volume to start from.
+func Ring(timer *TimerPlayer) error {
+	registerRinging(timer)
+	defer unregisterRinging(timer)
+
+	// whatever the reason to stop, the timer is not ringing anymore
+	defer timer.Silence()
+
+	volume := fadeIn(time.Now(), Overrides.Fade, Overrides.FadeFrom)
+	if Overrides.Crescendo {
+		from := Overrides.Volume * crescendoFrom
+		if Overrides.Fade > 0 {
+			from = Overrides.FadeFrom
+		}
+
+		volume = fadeIn(time.Now(), max(Overrides.Fade, crescendoTime), from)
+	}
+
+	limit := time.NewTimer(Overrides.MaxRing)
+	defer limit.Stop()
+	for {
+		stopped, err := playSound(timer.Silenced(), volume)
+		if err != nil || stopped {
+			return err
+		}
+
+		select {
+		case <-timer.Silenced():
+			return nil
+		case <-limit.C:
+			log.Printf("timer %s rang for %s, giving up", timer.Id, Overrides.MaxRing)
+			return nil
+		case <-time.After(ringPause):
+		}
+	}
+}
+
+// SilenceAll stops every timer ringing in this process
+func SilenceAll() {
+	ringingMu.Lock()
+	defer ringingMu.Unlock()
+
+	for _, timer := range ringing {
+		timer.Silence()
+	}
+}
+
+func registerRinging(timer *TimerPlayer) {
+	ringingMu.Lock()
+	ringing[timer.Id] = timer
+	ringingMu.Unlock()
+}
+
+func unregisterRinging(timer *TimerPlayer) {
+	ringingMu.Lock()
+	delete(ringing, timer.Id)
+	ringingMu.Unlock()
+}
+
+// playSound plays the sound once, reports whether it was stopped early
+func playSound(stop <-chan struct{}, volume func() float64) (bool, error) {
+	if soundDisabled {
+		return false, nil
+	}
+
+	dec, err := newDecoder(sound)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	ctx, ready, err := oto.NewContext(dec.SampleRate(), 2, 2)
+	return play(dec, stop, volume)
+}
+
+func play(dec decoder, stop <-chan struct{}, volume func() float64) (bool, error) {
+	ctx, err := audioContext()
 	if err != nil {
-		return err
+		return false, err
 	}
-	<-ready
 
-	player := ctx.NewPlayer(dec)
+	var src io.Reader = dec
+	if dec.SampleRate() != audioRate {
+		src = newResampler(dec, audioRate)
+	}
+
+	player := ctx.NewPlayer(src)
 	defer func() { _ = player.Close() }()
-	player.SetVolume(Overrides.Volume)
+	player.SetVolume(volume())
 	player.Play()
 
 	for player.IsPlaying() {
-		time.Sleep(10 * time.Millisecond)
+		select {
+		case <-stop:
+			return true, nil
+		case <-time.After(10 * time.Millisecond):
+			player.SetVolume(volume())
+		}
 	}
 
-	return nil
+	return false, nil
+}
+
+// SetAudioSinkEnv picks the output device for the ALSA plugins of PulseAudio and PipeWire,
+// they read it once the device is opened. Call it early, before any other thread runs.
+func SetAudioSinkEnv() {
+	if Overrides.AudioSink == "" {
+		return
+	}
+
+	log.Printf("audio sink requested: %s", Overrides.AudioSink)
+	_ = os.Setenv("PULSE_SINK", Overrides.AudioSink)
+	_ = os.Setenv("PIPEWIRE_NODE", Overrides.AudioSink)
+}
+
+// audioContext is created on the first playback and lives as long as the process,
+// its players are mixed, so the sounds may overlap
+func audioContext() (*oto.Context, error) {
+	audioMu.Lock()
+	defer audioMu.Unlock()
+
+	if audioCtx != nil {
+		return audioCtx, nil
+	}
+
+	ctx, ready, err := oto.NewContext(audioRate, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	<-ready
+
+	audioCtx = ctx
+	return ctx, nil
+}
+
+// AudioSink is an output device of PulseAudio or PipeWire
+type AudioSink struct {
+	Name        string
+	Description string
+}
+
+// ListAudioSinks asks pactl, which works with PipeWire too, empty if it's not installed
+func ListAudioSinks() []AudioSink {
+	// the field names are translated otherwise
+	cmd := exec.Command("pactl", "list", "sinks")
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("list audio sinks: %v", err)
+		return nil
+	}
+
+	var sinks []AudioSink
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		switch {
+		case !ok:
+		case key == "Name":
+			sinks = append(sinks, AudioSink{Name: value, Description: value})
+		case key == "Description" && len(sinks) > 0:
+			sinks[len(sinks)-1].Description = value
+		}
+	}
+
+	return sinks
 }
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fallbackSoundTheme is searched after the theme and all the themes it inherits
+const fallbackSoundTheme = "freedesktop"
+
+var soundExtensions = []string{".oga", ".ogg", ".wav", ".flac", ".mp3"}
+
+// ErrSoundDisabled means the sound theme has a name.disabled file, i.e. wants silence
+var ErrSoundDisabled = errors.New("disabled by the sound theme")
+
+// soundTheme is a parsed index.theme of the XDG sound theme spec
+type soundTheme struct {
+	dirs     []string
+	subdirs  []string
+	inherits []string
+}
+
+// ResolveSoundName finds the file of a sound like "complete" or "alarm-clock-elapsed"
+// in the desktop's sound theme, following the XDG sound theme spec
+func ResolveSoundName(name string) (string, error) {
+	return resolveSoundName(soundDirs(), desktopSoundTheme(), name)
+}
+
+func resolveSoundName(bases []string, themeName string, name string) (string, error) {
+	if name == "" || strings.ContainsRune(name, '/') {
+		return "", fmt.Errorf("invalid sound name %q", name)
+	}
+
+	themes := soundThemeChain(bases, themeName)
+
+	// the more specific names first, e.g. "alarm-clock-elapsed", then "alarm-clock", then "alarm"
+	for _, candidate := range soundNameFallbacks(name) {
+		for _, theme := range themes {
+			if path, disabled := theme.lookup(candidate); disabled {
+				return "", fmt.Errorf("sound %q: %w", name, ErrSoundDisabled)
+			} else if path != "" {
+				return path, nil
+			}
+		}
+
+		// unthemed sounds are placed right into the base directories
+		if path := findSound(bases, candidate); path != "" {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("sound %q not found in the sound themes", name)
+}
+
+// soundThemeChain is the theme, all the themes it inherits, then the fallback theme
+func soundThemeChain(bases []string, name string) []soundTheme {
+	var chain []soundTheme
+	visited := map[string]bool{}
+	queue := []string{name}
+	for len(queue) > 0 {
+		name, queue = queue[0], queue[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		theme := loadSoundTheme(bases, name)
+		chain = append(chain, theme)
+		queue = append(queue, theme.inherits...)
+		if len(queue) == 0 && !visited[fallbackSoundTheme] {
+			queue = append(queue, fallbackSoundTheme)
+		}
+	}
+
+	return chain
+}
+
+// lookup finds the sound in the stereo directories of the theme,
+// a name.disabled file means the theme wants silence
+func (t soundTheme) lookup(name string) (path string, disabled bool) {
+	for _, dir := range t.dirs {
+		for _, subdir := range t.subdirs {
+			base := filepath.Join(dir, subdir, name)
+			if fileExists(base + ".disabled") {
+				return "", true
+			}
+
+			for _, ext := range soundExtensions {
+				if fileExists(base + ext) {
+					return base + ext, false
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+func soundNameFallbacks(name string) []string {
+	names := []string{name}
+	for i := strings.LastIndexByte(name, '-'); i > 0; i = strings.LastIndexByte(name, '-') {
+		name = name[:i]
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func findSound(dirs []string, name string) string {
+	for _, dir := range dirs {
+		for _, ext := range soundExtensions {
+			if path := filepath.Join(dir, name+ext); fileExists(path) {
+				return path
+			}
+		}
+	}
+
+	return ""
+}
+
+// loadSoundTheme merges the theme from all the base directories, the first index.theme wins
+func loadSoundTheme(bases []string, name string) soundTheme {
+	theme := soundTheme{}
+	for _, base := range bases {
+		dir := filepath.Join(base, name)
+		if !fileExists(dir) {
+			continue
+		}
+
+		theme.dirs = append(theme.dirs, dir)
+		if theme.subdirs != nil {
+			continue
+		}
+
+		subdirs, inherits, err := parseSoundThemeIndex(filepath.Join(dir, "index.theme"))
+		if err != nil {
+			continue
+		}
+
+		theme.subdirs = subdirs
+		theme.inherits = inherits
+	}
+
+	if theme.subdirs == nil {
+		theme.subdirs = []string{"stereo"}
+	}
+
+	return theme
+}
+
+// parseSoundThemeIndex reads the stereo directories and the inherited themes of index.theme
+func parseSoundThemeIndex(path string) (subdirs []string, inherits []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var directories []string
+	profiles := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case section == "Sound Theme" && key == "Inherits":
+			inherits = splitList(value)
+		case section == "Sound Theme" && key == "Directories":
+			directories = splitList(value)
+		case key == "OutputProfile":
+			profiles[section] = value
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// surround sound directories are of no use for a short alarm
+	for _, dir := range directories {
+		if profile, ok := profiles[dir]; !ok || profile == "stereo" {
+			subdirs = append(subdirs, dir)
+		}
+	}
+
+	return subdirs, inherits, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// soundDirs are $XDG_DATA_HOME/sounds and $XDG_DATA_DIRS/sounds, in the order of preference
+func soundDirs() []string {
+	dirs := []string{filepath.Join(glib.GetUserDataDir(), "sounds")}
+	for _, dir := range glib.GetSystemDataDirs() {
+		dirs = append(dirs, filepath.Join(dir, "sounds"))
+	}
+
+	return dirs
+}
+
+// desktopSoundTheme is the theme chosen in GNOME settings, if available
+func desktopSoundTheme() string {
+	source := gio.SettingsSchemaSourceGetDefault()
+	if source == nil || source.Lookup("org.gnome.desktop.sound", true) == nil {
+		return fallbackSoundTheme
+	}
+
+	if theme := gio.NewSettings("org.gnome.desktop.sound").String("theme-name"); theme != "" {
+		return theme
+	}
+
+	return fallbackSoundTheme
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
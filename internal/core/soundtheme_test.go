@@ -0,0 +1,121 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeFiles creates the files under root, with the given content
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveSoundName(t *testing.T) {
+	// the same layout as $XDG_DATA_HOME/sounds and $XDG_DATA_DIRS/sounds
+	home := filepath.Join(t.TempDir(), "sounds")
+	system := filepath.Join(t.TempDir(), "sounds")
+	bases := []string{home, system}
+
+	writeFiles(t, system, map[string]string{
+		"child/index.theme":               "[Sound Theme]\nName=Child\nInherits=parent\nDirectories=stereo,5.1\n\n[stereo]\nOutputProfile=stereo\n\n[5.1]\nOutputProfile=5.1\n",
+		"child/stereo/alarm.oga":          "",
+		"child/stereo/bell.disabled":      "",
+		"child/5.1/surround.oga":          "",
+		"parent/index.theme":              "[Sound Theme]\nName=Parent\nDirectories=stereo\n",
+		"parent/stereo/alarm-clock.oga":   "",
+		"parent/stereo/bell.oga":          "",
+		"parent/stereo/complete.wav":      "",
+		"parent/stereo/surround.oga":      "",
+		"freedesktop/stereo/message.oga":  "",
+		"freedesktop/stereo/complete.oga": "",
+		"unthemed.ogg":                    "",
+	})
+
+	// the user's own files come first
+	writeFiles(t, home, map[string]string{
+		"child/stereo/complete.flac": "",
+	})
+
+	tests := []struct {
+		name     string
+		expected string
+		disabled bool
+	}{
+		{name: "alarm", expected: filepath.Join(system, "child/stereo/alarm.oga")},
+		{name: "complete", expected: filepath.Join(home, "child/stereo/complete.flac")},
+		{name: "message", expected: filepath.Join(system, "freedesktop/stereo/message.oga")},
+		{name: "unthemed", expected: filepath.Join(system, "unthemed.ogg")},
+		// the more specific name wins, even if it's only inherited
+		{name: "alarm-clock-elapsed", expected: filepath.Join(system, "parent/stereo/alarm-clock.oga")},
+		{name: "alarm-other", expected: filepath.Join(system, "child/stereo/alarm.oga")},
+		// surround directories are skipped
+		{name: "surround", expected: filepath.Join(system, "parent/stereo/surround.oga")},
+		{name: "bell", disabled: true},
+		{name: "bell-ringing", disabled: true},
+		{name: "missing"},
+		{name: "../child"},
+		{name: ""},
+	}
+
+	for _, test := range tests {
+		path, err := resolveSoundName(bases, "child", test.name)
+		switch {
+		case test.disabled:
+			if !errors.Is(err, ErrSoundDisabled) {
+				t.Errorf("resolveSoundName(%q) = %q, %v, expected ErrSoundDisabled", test.name, path, err)
+			}
+		case test.expected == "":
+			if err == nil {
+				t.Errorf("resolveSoundName(%q) = %q, expected an error", test.name, path)
+			}
+		case err != nil || path != test.expected:
+			t.Errorf("resolveSoundName(%q) = %q, %v, expected %q", test.name, path, err, test.expected)
+		}
+	}
+}
+
+func TestSoundThemeChain(t *testing.T) {
+	base := t.TempDir()
+	writeFiles(t, base, map[string]string{
+		"a/index.theme": "[Sound Theme]\nInherits=b, c\n",
+		"b/index.theme": "[Sound Theme]\nInherits=a\n",
+		"c/index.theme": "[Sound Theme]\nInherits=freedesktop\n",
+	})
+
+	// inherited breadth first, a loop is cut, the fallback theme comes once at the end
+	chain := soundThemeChain([]string{base}, "a")
+	var names []string
+	for _, theme := range chain {
+		if len(theme.dirs) > 0 {
+			names = append(names, filepath.Base(theme.dirs[0]))
+		} else {
+			names = append(names, "-")
+		}
+	}
+
+	expected := []string{"a", "b", "c", "-"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("chain = %v, expected %v", names, expected)
+	}
+}
+
+func TestSoundNameFallbacks(t *testing.T) {
+	names := soundNameFallbacks("alarm-clock-elapsed")
+	expected := []string{"alarm-clock-elapsed", "alarm-clock", "alarm"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("soundNameFallbacks() = %v, expected %v", names, expected)
+	}
+}
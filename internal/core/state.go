@@ -0,0 +1,216 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RestoreName is owned by the process that restores the saved timers, for as long as it runs
+const RestoreName = AppId + ".Restore"
+
+// SavedTimer is the state of a running timer, kept on disk to survive a crash or a logout
+type SavedTimer struct {
+	Id        string          `json:"id"`
+	Title     string          `json:"title"`
+	Duration  time.Duration   `json:"duration"`
+	Deadline  time.Time       `json:"deadline"`
+	Paused    bool            `json:"paused"`
+	PausedFor time.Duration   `json:"pausedFor"`
+	Elapsed   time.Duration   `json:"elapsed"`
+	Phase     int             `json:"phase"`
+	Laps      []time.Duration `json:"laps,omitempty"`
+	Options   TimerOptions    `json:"options"`
+}
+
+func timersDir() string {
+	return path.Join(StateDir, "timers")
+}
+
+// IsExpired means the deadline (of the last phase) has passed, paused timers and stopwatches never expire
+func (s SavedTimer) IsExpired() bool {
+	return !s.Paused && !s.Options.Stopwatch && !time.Now().Before(s.End())
+}
+
+// End is the deadline of the whole timer, including the phases of a sequence after the current one
+func (s SavedTimer) End() time.Time {
+	if s.Phase+1 >= len(s.Options.Sequence) {
+		return s.Deadline
+	}
+
+	return s.Deadline.Add(TotalDuration(s.Options.Sequence[s.Phase+1:]))
+}
+
+func (s SavedTimer) elapsed() time.Duration {
+	if s.Paused {
+		return s.Elapsed
+	}
+
+	return s.Duration - time.Until(s.Deadline)
+}
+
+// RestoreTimer recreates a saved timer with the same id, Start it as usual
+func RestoreTimer(saved SavedTimer) (*TimerPlayer, error) {
+	if saved.IsExpired() {
+		return nil, fmt.Errorf("timer %s expired at %s", saved.Id, saved.End().Format(time.DateTime))
+	}
+
+	var p *TimerPlayer
+	var err error
+	if saved.Options.Stopwatch {
+		p, err = NewStopwatchPlayer(saved.Title)
+	} else {
+		p, err = NewTimerPlayer(int(saved.Duration.Round(time.Second)/time.Second), saved.Title)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.Id = saved.Id
+	p.Options = saved.Options
+	p.phase = saved.Phase
+	p.laps = saved.Laps
+	p.duration = saved.Duration
+	p.restoredAt = saved.elapsed()
+	p.isPaused = saved.Paused
+
+	// the phases of a sequence that ended meanwhile are skipped
+	for p.restoredAt >= p.duration && p.hasNextPhase() {
+		p.restoredAt -= p.duration
+		p.phase++
+		p.duration = p.Options.Sequence[p.phase].Duration
+	}
+	if p.isPaused {
+		p.playbackStatus = "Paused"
+	}
+
+	return p, nil
+}
+
+// LoadSavedTimer reads a single timer by id
+func LoadSavedTimer(id string) (SavedTimer, error) {
+	var saved SavedTimer
+	data, err := os.ReadFile(path.Join(timersDir(), id+".json"))
+	if err != nil {
+		return saved, err
+	}
+
+	err = json.Unmarshal(data, &saved)
+	return saved, err
+}
+
+// PendingTimers are the saved timers not running in any process right now
+func PendingTimers() []SavedTimer {
+	entries, err := os.ReadDir(timersDir())
+	if err != nil {
+		return nil
+	}
+
+	running, err := ListTimers()
+	if err != nil {
+		log.Printf("list running timers: %v", err)
+	}
+
+	var pending []SavedTimer
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || slices.ContainsFunc(running, func(t RemoteTimer) bool { return t.Id == id }) {
+			continue
+		}
+
+		saved, err := LoadSavedTimer(id)
+		if err != nil {
+			log.Printf("load saved timer %s: %v", id, err)
+			continue
+		}
+
+		pending = append(pending, saved)
+	}
+
+	return pending
+}
+
+// ClaimRestore reports whether this process is the one to restore the saved timers.
+// The first launch (or the daemon) keeps the bus name until it exits, so the others don't restore them again.
+func ClaimRestore() bool {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		log.Printf("connect to session bus, timers won't be restored: %v", err)
+		return false
+	}
+
+	reply, err := conn.RequestName(RestoreName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		log.Printf("request bus, timers won't be restored: %v", err)
+		return false
+	}
+
+	return reply == dbus.RequestNameReplyPrimaryOwner || reply == dbus.RequestNameReplyAlreadyOwner
+}
+
+// RestoreDetached restores a saved timer in a new process, so that it outlives the caller.
+// The process can't be handed off to, so it gets the cache directory of this one.
+func RestoreDetached(id string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-restore", id}
+	if !isDefaultCacheDir() {
+		args = append(args, "-cache-dir", CacheDir)
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	return cmd.Start()
+}
+
+// ForgetTimer removes the saved state, e.g. after reporting it as missed
+func ForgetTimer(id string) {
+	err := os.Remove(path.Join(timersDir(), id+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("remove saved timer %s: %v", id, err)
+	}
+}
+
+func (p *TimerPlayer) save() {
+	saved := SavedTimer{
+		Id:        p.Id,
+		Title:     p.Name,
+		Duration:  p.duration,
+		Deadline:  time.Now().Round(0).Add(p.duration - p.elapsed()),
+		Paused:    p.isPaused,
+		PausedFor: p.pausedFor,
+		Elapsed:   p.elapsed(),
+		Phase:     p.phase,
+		Laps:      p.laps,
+		Options:   p.Options,
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		log.Printf("save timer: %v", err)
+		return
+	}
+
+	// write and rename, so that a crash never leaves a broken file
+	filename := path.Join(timersDir(), p.Id+".json")
+	_ = os.MkdirAll(timersDir(), 0755)
+	if err = os.WriteFile(filename+".tmp", data, 0644); err == nil {
+		err = os.Rename(filename+".tmp", filename)
+	}
+
+	if err != nil {
+		log.Printf("save timer: %v", err)
+	}
+}
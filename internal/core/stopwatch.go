@@ -0,0 +1,70 @@
+package core
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"log"
+	"time"
+)
+
+// NewStopwatchPlayer creates a timer that counts up until cancelled
+func NewStopwatchPlayer(name string) (*TimerPlayer, error) {
+	p, err := NewTimerPlayer(1, name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.duration = 0
+	p.Options.Stopwatch = true
+	return p, nil
+}
+
+func (p *TimerPlayer) isStopwatch() bool {
+	return p.Options.Stopwatch
+}
+
+// Lap records a split of a stopwatch, the latest one is shown in the metadata
+func (p *TimerPlayer) Lap() error {
+	if !p.isStopwatch() {
+		return fmt.Errorf("laps are only supported by a stopwatch")
+	}
+
+	p.laps = append(p.laps, p.elapsed())
+	log.Printf("timer %s: lap %d, %s", p.Id, len(p.laps), FormatDuration(p.lastSplit()))
+
+	p.emitPropertiesChanged("org.mpris.MediaPlayer2.Player", map[string]dbus.Variant{
+		"Metadata": dbus.MakeVariant(p.metadata(p.progressText, p.img)),
+	})
+	p.save()
+	return nil
+}
+
+// lastSplit is the time between the latest two laps
+func (p *TimerPlayer) lastSplit() time.Duration {
+	switch len(p.laps) {
+	case 0:
+		return 0
+	case 1:
+		return p.laps[0]
+	default:
+		return p.laps[len(p.laps)-1] - p.laps[len(p.laps)-2]
+	}
+}
+
+func (p *TimerPlayer) lapAlbum() string {
+	return fmt.Sprintf("Lap %d · %s", len(p.laps), FormatDuration(p.lastSplit()))
+}
+
+// stopwatchProgress loops the ring every minute during the first hour, then every hour
+func stopwatchProgress(elapsed time.Duration) float64 {
+	loop := stopwatchLoop(elapsed)
+	return float64(elapsed%loop) / float64(loop) * 100
+}
+
+func stopwatchLoop(elapsed time.Duration) time.Duration {
+	if elapsed >= time.Hour {
+		return time.Hour
+	}
+
+	return time.Minute
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	StyleRing     = "ring"
+	StylePie      = "pie"
+	StyleBar      = "bar"
+	StyleSegments = "segments"
+	StyleDigits   = "digits"
+
+	// maxSegments keeps the segments visible, longer timers get a segment per several minutes
+	maxSegments = 60
+	segmentGap  = 2
+	pieRadius   = width/2 - padding - 8
+	barLength   = width - 4*padding
+	digitsSize  = 24
+	// farDistance is more than the widest blur, the exact distance doesn't matter beyond
+	farDistance = 16
+)
+
+// Styles are the progress visuals, in the order of the preferences, the custom templates are added last
+var Styles = []string{StyleRing, StylePie, StyleBar, StyleSegments, StyleDigits}
+
+// Frame is what the progress image shows at a moment
+type Frame struct {
+	Progress float64       // percent
+	Left     time.Duration // shown by the digits, the elapsed time for a stopwatch
+	Duration time.Duration // the segments are its minutes
+	Title    string        // the rest is for the custom templates
+	Phase    string
+	Step     int
+	Steps    int
+}
+
+// ParseStyle accepts the name of a style, in any case.
+// An invalid template is accepted as well, it falls back to the ring.
+func ParseStyle(value string) (string, error) {
+	style := strings.ToLower(strings.TrimSpace(value))
+	if !slices.Contains(Styles, style) && !isTemplateStyle(style) {
+		return "", fmt.Errorf("unknown style %q, expected one of %s", value, strings.Join(Styles, ", "))
+	}
+
+	return style, nil
+}
+
+// shape is the geometry of a style in the coordinates of the SVG,
+// the distances from the edges are negative inside
+type shape interface {
+	track(x, y float64) float64
+	progress(x, y float64) float64
+}
+
+func newShape(style string, frame Frame) shape {
+	switch style {
+	case StylePie:
+		return newPie(frame.Progress)
+	case StyleBar:
+		return newBar(frame.Progress)
+	case StyleSegments:
+		return newSegments(frame)
+	case StyleDigits:
+		r := newRing(frame.Progress)
+		r.width = r.baseWidth
+		r.rounded = false
+		r.rotate(-90)
+		return r
+	}
+
+	return newRing(frame.Progress)
+}
+
+func (r ring) track(x, y float64) float64 {
+	return math.Abs(length(x, y)-r.radius) - r.baseWidth/2
+}
+
+func (r ring) progress(x, y float64) float64 {
+	if r.sweep == 0 {
+		return farDistance
+	}
+
+	return r.distance(x, y)
+}
+
+// pie is a disc filled clockwise
+type pie struct {
+	ring
+}
+
+func newPie(progress float64) pie {
+	r := newRing(progress)
+	r.radius = pieRadius
+	r.rotate(-90)
+	return pie{r}
+}
+
+func (p pie) track(x, y float64) float64 {
+	return length(x, y) - p.radius
+}
+
+func (p pie) progress(x, y float64) float64 {
+	if p.sweep == 0 {
+		return farDistance
+	}
+
+	if p.sweep >= 2*math.Pi || p.covers(x, y) {
+		return length(x, y) - p.radius
+	}
+
+	return min(p.edgeDistance(x, y, p.ends[0]), p.edgeDistance(x, y, p.ends[1]))
+}
+
+// edgeDistance is the distance from the radius in the given direction
+func (p pie) edgeDistance(x, y float64, end [2]float64) float64 {
+	along := max(0, min(p.radius, x*end[0]+y*end[1]))
+	return length(x-end[0]*along, y-end[1]*along)
+}
+
+// bar is a horizontal bar filled left to right
+type bar struct {
+	fill    float64
+	height  float64
+	corners float64
+}
+
+func newBar(progress float64) bar {
+	b := bar{
+		fill:   barLength * max(0, min(100, progress)) / 100,
+		height: strokeWidth,
+	}
+
+	if Overrides.Rounded {
+		b.corners = strokeWidth / 2
+	}
+
+	return b
+}
+
+func (b bar) track(x, y float64) float64 {
+	return roundedBox(x, y, barLength, b.height, b.corners)
+}
+
+func (b bar) progress(x, y float64) float64 {
+	if b.fill == 0 {
+		return farDistance
+	}
+
+	// the fill starts where the track does
+	return roundedBox(x+(barLength-b.fill)/2, y, b.fill, b.height, min(b.corners, b.fill/2))
+}
+
+// roundedBox is the distance from a box centered at the origin
+func roundedBox(x, y, w, h, radius float64) float64 {
+	dx := math.Abs(x) - w/2 + radius
+	dy := math.Abs(y) - h/2 + radius
+	return length(max(dx, 0), max(dy, 0)) + min(max(dx, dy), 0) - radius
+}
+
+// segments is a ring split into a segment per minute
+type segments struct {
+	ring
+	step  float64
+	total []ring
+	done  []ring
+}
+
+func newSegments(frame Frame) segments {
+	count, _ := segmentCount(frame.Duration)
+	s := segments{ring: newRing(0), step: 2 * math.Pi / float64(count)}
+	s.rounded = false
+	s.rotate(-90)
+
+	gap := min(segmentGap/s.radius, s.step/3)
+	filled := s.step * float64(count) * max(0, min(100, frame.Progress)) / 100
+	for i := range count {
+		start := s.origin + float64(i)*s.step + gap/2
+		s.total = append(s.total, s.arc(start, s.step-gap))
+		if done := min(filled-float64(i)*s.step, s.step) - gap/2; done > 0 {
+			s.done = append(s.done, s.arc(start, min(done, s.step-gap)))
+		}
+	}
+
+	return s
+}
+
+func (s segments) arc(start, sweep float64) ring {
+	r := s.ring
+	r.origin = start
+	r.sweep = sweep
+	r.ends = [2][2]float64{{math.Cos(start), math.Sin(start)}, {math.Cos(start + sweep), math.Sin(start + sweep)}}
+	return r
+}
+
+func (s segments) track(x, y float64) float64 {
+	return s.nearest(s.total, x, y)
+}
+
+func (s segments) progress(x, y float64) float64 {
+	return s.nearest(s.done, x, y)
+}
+
+// nearest looks at the segment in the direction of the point and its neighbours only
+func (s segments) nearest(arcs []ring, x, y float64) float64 {
+	if dr := math.Abs(length(x, y)-s.radius) - s.width/2; dr > farDistance || len(arcs) == 0 {
+		return max(dr, farDistance)
+	}
+
+	angle := math.Atan2(y, x) - s.origin
+	for angle < 0 {
+		angle += 2 * math.Pi
+	}
+
+	i := int(angle / s.step)
+	distance := float64(farDistance)
+	for _, j := range []int{i - 1, i, i + 1} {
+		if j >= 0 && j < len(arcs) {
+			distance = min(distance, arcs[j].distance(x, y))
+		}
+	}
+
+	// the last segment neighbours the first one
+	if i == len(s.total)-1 || i == 0 {
+		distance = min(distance, arcs[0].distance(x, y), arcs[len(arcs)-1].distance(x, y))
+	}
+
+	return distance
+}
+
+// segmentCount is the number of segments and the minutes in each
+func segmentCount(duration time.Duration) (count int, minutes int) {
+	total := max(1, int(math.Ceil(duration.Minutes())))
+	minutes = (total + maxSegments - 1) / maxSegments
+	return (total + minutes - 1) / minutes, minutes
+}
+
+// digitsText is the time left as the digits style shows it, without seconds after an hour
+func digitsText(left time.Duration) string {
+	left = left.Round(time.Second)
+	if left >= time.Hour {
+		return fmt.Sprintf("%dh%02d", left/time.Hour, left%time.Hour/time.Minute)
+	}
+
+	return fmt.Sprintf("%02d:%02d", left/time.Minute, left%time.Minute/time.Second)
+}
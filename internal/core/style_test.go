@@ -0,0 +1,90 @@
+package core
+
+import (
+	"path"
+	"testing"
+	"time"
+)
+
+func TestSegmentCount(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		count    int
+		minutes  int
+	}{
+		{duration: 0, count: 1, minutes: 1},
+		{duration: 30 * time.Second, count: 1, minutes: 1},
+		{duration: 5 * time.Minute, count: 5, minutes: 1},
+		{duration: 5*time.Minute + time.Second, count: 6, minutes: 1},
+		{duration: time.Hour, count: 60, minutes: 1},
+		{duration: time.Hour + time.Minute, count: 31, minutes: 2},
+		{duration: 90 * time.Minute, count: 45, minutes: 2},
+		{duration: 3 * time.Hour, count: 60, minutes: 3},
+		{duration: 24 * time.Hour, count: 60, minutes: 24},
+	}
+
+	for _, test := range tests {
+		count, minutes := segmentCount(test.duration)
+		if count != test.count || minutes != test.minutes {
+			t.Errorf("segmentCount(%s) = %d, %d, expected %d, %d", test.duration, count, minutes, test.count, test.minutes)
+		}
+	}
+}
+
+func TestDigitsText(t *testing.T) {
+	tests := []struct {
+		left     time.Duration
+		expected string
+	}{
+		{left: 0, expected: "00:00"},
+		{left: 499 * time.Millisecond, expected: "00:00"},
+		{left: 500 * time.Millisecond, expected: "00:01"},
+		{left: 59 * time.Second, expected: "00:59"},
+		{left: 10*time.Minute + 7*time.Second, expected: "10:07"},
+		{left: 59*time.Minute + 59*time.Second, expected: "59:59"},
+		{left: 59*time.Minute + 59*time.Second + 600*time.Millisecond, expected: "1h00"},
+		{left: 2*time.Hour + 30*time.Minute + 59*time.Second, expected: "2h30"},
+		{left: 100 * time.Hour, expected: "100h00"},
+	}
+
+	for _, test := range tests {
+		if text := digitsText(test.left); text != test.expected {
+			t.Errorf("digitsText(%s) = %q, expected %q", test.left, text, test.expected)
+		}
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	DataDir = t.TempDir()
+	writeFiles(t, path.Join(DataDir, "templates"), map[string]string{
+		"broken" + templateExt: "{{.Missing",
+	})
+
+	tests := []struct {
+		value    string
+		expected string
+		fails    bool
+	}{
+		{value: "ring", expected: StyleRing},
+		{value: " Digits ", expected: StyleDigits},
+		{value: "SEGMENTS", expected: StyleSegments},
+		// an invalid template falls back to the ring when it's drawn
+		{value: "broken", expected: "broken"},
+		{value: "circle", fails: true},
+		{value: "", fails: true},
+	}
+
+	for _, test := range tests {
+		style, err := ParseStyle(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseStyle(%q) = %q, expected an error", test.value, style)
+			}
+			continue
+		}
+
+		if err != nil || style != test.expected {
+			t.Errorf("ParseStyle(%q) = %q, %v, expected %q", test.value, style, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"image"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const templateExt = ".svg.tmpl"
+
+// templateHashes are the custom styles, the hash tells the cached files of the template's versions apart
+var templateHashes = map[string]string{}
+
+// templatesDir holds the user's own styles, DataDir/templates/<style>.svg.tmpl
+func templatesDir() string {
+	return path.Join(DataDir, "templates")
+}
+
+// loadTemplates adds every valid template as a style after the built-in ones,
+// which can't be replaced. The invalid ones fall back to the ring.
+func loadTemplates() {
+	files, _ := filepath.Glob(path.Join(templatesDir(), "*"+templateExt))
+	for _, filename := range files {
+		style := strings.ToLower(strings.TrimSuffix(filepath.Base(filename), templateExt))
+		if style == "" || slices.Contains(Styles, style) {
+			log.Printf("template %s: %q is a built-in style", filename, style)
+			continue
+		}
+
+		t, hash, err := loadTemplate(filename)
+		if err != nil {
+			log.Printf("template %s: %v, using the built-in one", filename, err)
+			continue
+		}
+
+		svgTpls[style] = t
+		templateHashes[style] = hash
+		Styles = append(Styles, style)
+	}
+}
+
+// loadTemplate renders a sample frame, so the errors show up now rather than in the middle of a timer
+func loadTemplate(filename string) (*template.Template, string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t, err := template.New(filepath.Base(filename)).Parse(string(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	sample := Frame{
+		Progress: 40,
+		Left:     3 * time.Minute,
+		Duration: 5 * time.Minute,
+		Title:    "Timer",
+		Phase:    "Work",
+		Step:     1,
+		Steps:    2,
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, newSvgParams("", sample)); err != nil {
+		return nil, "", err
+	}
+
+	if err = checkSVG(buf.Bytes()); err != nil {
+		return nil, "", fmt.Errorf("invalid SVG: %w", err)
+	}
+
+	if _, err = oksvg.ReadIconStream(&buf); err != nil {
+		return nil, "", fmt.Errorf("invalid SVG: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return t, hex.EncodeToString(sum[:4]), nil
+}
+
+// checkSVG wants well-formed XML with the svg root, oksvg accepts anything without elements
+func checkSVG(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := ""
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if start, ok := token.(xml.StartElement); ok && root == "" {
+			root = start.Name.Local
+		}
+	}
+
+	if root != "svg" {
+		return fmt.Errorf("the root element is %q, not svg", root)
+	}
+
+	return nil
+}
+
+// isTemplateStyle reports whether the style is a template of templatesDir, loaded or not
+func isTemplateStyle(style string) bool {
+	return templateHashes[style] != "" || (style != "" && fileExists(path.Join(templatesDir(), style+templateExt)))
+}
+
+// lastTemplate is the image renderTemplate drew last, the tray and the preview ask for the same one many times a second
+var lastTemplate struct {
+	sync.Mutex
+	key string
+	img *image.RGBA
+}
+
+// renderTemplate rasterizes the SVG of a custom style with oksvg in memory, nil if it fails
+func renderTemplate(style string, frame Frame, size int, crop float64) *image.RGBA {
+	key := fmt.Sprintf("%s/%s/%d/%v", Overrides.Color, frameFootprint(style, frame), size, crop)
+	lastTemplate.Lock()
+	defer lastTemplate.Unlock()
+	if lastTemplate.key == key {
+		return lastTemplate.img
+	}
+
+	frame.Progress = fileProgress(style, frame.Progress)
+	var buf bytes.Buffer
+	if err := svgTpls[style].Execute(&buf, newSvgParams(style, frame)); err != nil {
+		log.Printf("template %s: %v, using the built-in one", style, err)
+		return nil
+	}
+
+	icon, err := oksvg.ReadIconStream(&buf)
+	if err != nil {
+		log.Printf("render template: %v", err)
+		return nil
+	}
+
+	scale := float64(size) / (float64(width) - 2*crop)
+	icon.SetTarget(-crop*scale, -crop*scale, float64(width)*scale, float64(height)*scale)
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	icon.Draw(rasterx.NewDasher(size, size, rasterx.NewScannerGV(size, size, img, img.Bounds())), 1)
+
+	lastTemplate.key = key
+	lastTemplate.img = img
+	return img
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	DataDir = t.TempDir()
+	CacheDir = t.TempDir()
+	Overrides.Color = fallbackColor
+	writeFiles(t, templatesDir(), map[string]string{
+		"square" + templateExt: `<svg width="{{.Width}}" height="{{.Height}}"><rect x="32" y="32" width="{{.Progress}}" height="64" fill="{{.FgStrokeColor}}" /></svg>`,
+	})
+
+	tpl, hash, err := loadTemplate(path.Join(templatesDir(), "square"+templateExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svgTpls["square"] = tpl
+	templateHashes["square"] = hash
+	t.Cleanup(func() {
+		delete(svgTpls, "square")
+		delete(templateHashes, "square")
+	})
+
+	img := renderFrame("square", Frame{Progress: 50, Left: time.Minute, Duration: 2 * time.Minute}, width, 0)
+	// the same second and percent is drawn once
+	if again := renderFrame("square", Frame{Progress: 50.2, Left: time.Minute, Duration: 2 * time.Minute}, width, 0); again != img {
+		t.Errorf("the same frame was drawn again")
+	}
+
+	for _, point := range []struct {
+		x, y   int
+		filled bool
+	}{{x: 40, y: 64, filled: true}, {x: 90, y: 64, filled: false}} {
+		if _, _, _, a := img.At(point.x, point.y).RGBA(); (a > 0) != point.filled {
+			t.Errorf("pixel at %d,%d: alpha %d, expected filled = %v", point.x, point.y, a, point.filled)
+		}
+	}
+
+	// the frames drawn in-process aren't cached
+	if entries, _ := os.ReadDir(CacheDir); len(entries) > 0 {
+		t.Errorf("expected no files in the cache, got %d", len(entries))
+	}
+}
@@ -6,27 +6,125 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
-func TimeFromPreset(preset string) time.Time {
-	partsLen := len(strings.Split(preset, ":"))
-	switch partsLen {
-	case 2:
-		result, err := time.Parse("04:05", preset)
-		if err != nil {
-			log.Fatalf("parse preset %s: %v", preset, err)
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+}
+
+// ParseDuration understands a plain number of seconds ("90"),
+// clock notation ("MM:SS", "HH:MM:SS") and units ("90s", "25m", "1h30m", "1.5h", "1h 30 min").
+// The duration is at least a second.
+func ParseDuration(value string) (time.Duration, error) {
+	d, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if d.Round(time.Second) == 0 {
+		return 0, fmt.Errorf("duration %q: must be at least 1 second", strings.TrimSpace(value))
+	}
+
+	return d, nil
+}
+
+// parseDuration is ParseDuration allowing zero
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("duration %q: must not be negative", value)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if strings.Contains(value, ":") {
+		return parseClock(value)
+	}
+
+	return parseUnits(value)
+}
+
+func parseClock(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("duration %q: too many parts, expected MM:SS or HH:MM:SS", value)
+	}
+
+	var result time.Duration
+	units := []time.Duration{time.Second, time.Minute, time.Hour}
+	for i, part := range parts {
+		num, err := strconv.Atoi(part)
+		if err != nil || num < 0 {
+			return 0, fmt.Errorf("duration %q: %q is not a number", value, part)
 		}
-		return result
-	case 3:
-		result, err := time.Parse("15:04:05", preset)
+
+		// the leading part may overflow, e.g. 90:00
+		if i > 0 && num > 59 {
+			return 0, fmt.Errorf("duration %q: %q is out of range, expected 0-59", value, part)
+		}
+
+		// the last part is always seconds
+		result += time.Duration(num) * units[len(parts)-1-i]
+	}
+
+	return result, nil
+}
+
+func parseUnits(value string) (time.Duration, error) {
+	var result time.Duration
+	rest := value
+	for rest != "" {
+		numEnd := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+		if numEnd == 0 {
+			return 0, fmt.Errorf("duration %q: expected a number at %q", value, rest)
+		}
+		if numEnd == -1 {
+			return 0, fmt.Errorf("duration %q: missing unit after %s, use s, m or h", value, rest)
+		}
+
+		num, err := strconv.ParseFloat(rest[:numEnd], 64)
 		if err != nil {
-			log.Fatalf("parse preset %s: %v", preset, err)
+			return 0, fmt.Errorf("duration %q: %q is not a number", value, rest[:numEnd])
 		}
-		return result
-	default:
-		log.Printf("parse preset %s: too many parts", preset)
-		return time.Time{}
+
+		rest = strings.TrimLeft(rest[numEnd:], " ")
+		unitEnd := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+		if unitEnd == -1 {
+			unitEnd = len(rest)
+		}
+
+		unit, ok := durationUnits[rest[:unitEnd]]
+		if !ok {
+			return 0, fmt.Errorf("duration %q: unknown unit %q, use s, m or h", value, rest[:unitEnd])
+		}
+
+		result += time.Duration(num * float64(unit))
+		rest = strings.TrimLeft(rest[unitEnd:], " ,")
 	}
+
+	return result, nil
+}
+
+// TimeFromPreset accepts anything ParseDuration does, as long as it's less than a day
+func TimeFromPreset(preset string) (time.Time, error) {
+	d, err := ParseDuration(preset)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse preset: %w", err)
+	}
+
+	if d >= 24*time.Hour {
+		return time.Time{}, fmt.Errorf("parse preset %s: must be less than 24 hours", preset)
+	}
+
+	return time.Time{}.Add(d.Round(time.Second)), nil
 }
 
 func TimeFromParts(hours int, minutes int, seconds int) time.Time {
@@ -109,3 +207,59 @@ func FormatDuration(d time.Duration) string {
 	buf[4] = '0' + byte(s%10)
 	return string(buf)
 }
+
+// ParseAlarm accepts "HH:MM", "HH:MM:SS" and the same with a "YYYY-MM-DD " prefix, in local time.
+// Without a date the alarm is set for the next occurrence of that time.
+func ParseAlarm(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	date, clock, hasDate := strings.Cut(value, " ")
+	if !hasDate {
+		clock = date
+		date = now.Format(time.DateOnly)
+	}
+
+	layout := "15:04"
+	if strings.Count(clock, ":") == 2 {
+		layout = time.TimeOnly
+	}
+
+	at, err := time.ParseInLocation(time.DateOnly+" "+layout, date+" "+clock, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("alarm %q: expected [YYYY-MM-DD ]HH:MM[:SS]", value)
+	}
+
+	if !at.After(now) {
+		if hasDate {
+			return time.Time{}, fmt.Errorf("alarm %q is in the past", value)
+		}
+
+		at = time.Date(at.Year(), at.Month(), at.Day()+1, at.Hour(), at.Minute(), at.Second(), 0, time.Local)
+	}
+
+	return at, nil
+}
+
+// AlarmFromParts is the next occurrence of the given time of day
+func AlarmFromParts(hours int, minutes int, seconds int) time.Time {
+	now := time.Now()
+	at := time.Date(now.Year(), now.Month(), now.Day(), hours, minutes, seconds, 0, time.Local)
+	if !at.After(now) {
+		at = time.Date(now.Year(), now.Month(), now.Day()+1, hours, minutes, seconds, 0, time.Local)
+	}
+
+	return at
+}
+
+// SecondsUntil is at least a second, an alarm due in less than that still runs
+func SecondsUntil(at time.Time) int {
+	return max(int(time.Until(at).Round(time.Second)/time.Second), 1)
+}
+
+// FormatAlarm omits the date unless the alarm is more than a day away
+func FormatAlarm(at time.Time) string {
+	if time.Until(at) >= 24*time.Hour {
+		return at.Format("Jan 2 15:04:05")
+	}
+
+	return at.Format(time.TimeOnly)
+}
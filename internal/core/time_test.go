@@ -0,0 +1,85 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		fails    bool
+	}{
+		{value: "90", expected: 90 * time.Second},
+		{value: "90s", expected: 90 * time.Second},
+		{value: "25m", expected: 25 * time.Minute},
+		{value: "1h30m", expected: 90 * time.Minute},
+		{value: "1h 30 min", expected: 90 * time.Minute},
+		{value: "1.5h", expected: 90 * time.Minute},
+		{value: " 2 Minutes ", expected: 2 * time.Minute},
+		{value: "1:30", expected: 90 * time.Second},
+		{value: "1:30:00", expected: 90 * time.Minute},
+		{value: "90:00", expected: 90 * time.Minute},
+		{value: "0.5s", expected: 500 * time.Millisecond},
+		{value: "", fails: true},
+		{value: "0", fails: true},
+		{value: "0s", fails: true},
+		{value: "0.4s", fails: true},
+		{value: "0:00", fails: true},
+		{value: "-5", fails: true},
+		{value: "25", expected: 25 * time.Second},
+		{value: "25x", fails: true},
+		{value: "m", fails: true},
+		{value: "1.2.3m", fails: true},
+	}
+
+	for _, test := range tests {
+		d, err := ParseDuration(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %s, expected an error", test.value, d)
+			}
+			continue
+		}
+
+		if err != nil || d != test.expected {
+			t.Errorf("ParseDuration(%q) = %s, %v, expected %s", test.value, d, err, test.expected)
+		}
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		fails    bool
+	}{
+		{value: "0:45", expected: 45 * time.Second},
+		{value: "05:00", expected: 5 * time.Minute},
+		{value: "1:02:03", expected: time.Hour + 2*time.Minute + 3*time.Second},
+		{value: "100:00", expected: 100 * time.Minute},
+		{value: "1:90", fails: true},
+		{value: "1:60:00", fails: true},
+		{value: "1:00:60", fails: true},
+		{value: "1:2:3:4", fails: true},
+		{value: "1:", fails: true},
+		{value: ":30", fails: true},
+		{value: "1:-5", fails: true},
+		{value: "a:30", fails: true},
+	}
+
+	for _, test := range tests {
+		d, err := parseClock(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("parseClock(%q) = %s, expected an error", test.value, d)
+			}
+			continue
+		}
+
+		if err != nil || d != test.expected {
+			t.Errorf("parseClock(%q) = %s, %v, expected %s", test.value, d, err, test.expected)
+		}
+	}
+}
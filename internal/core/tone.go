@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+const (
+	// no resampling needed
+	toneRate = audioRate
+
+	// the warnings sound higher and shorter than the default alarm
+	warningTone  = 1320
+	warningPulse = 90 * time.Millisecond
+
+	// the cues are quiet and short, so that they don't get annoying
+	tickTone       = 2600
+	tickPulse      = 6 * time.Millisecond
+	chimeTone      = 880
+	chimeLength    = 600 * time.Millisecond
+	countdownTone  = 660
+	countdownPulse = 120 * time.Millisecond
+
+	// fade in and out of every pulse, so that it doesn't click
+	toneFade = 5 * time.Millisecond
+)
+
+// pcmBuffer is a synthesized sound, ready to be played
+type pcmBuffer struct {
+	*bytes.Reader
+}
+
+func (b pcmBuffer) SampleRate() int {
+	return toneRate
+}
+
+// beep synthesizes count sine pulses of the given frequency, separated by gaps
+func beep(freq float64, pulse time.Duration, gap time.Duration, count int) decoder {
+	pulseLen := int(pulse.Seconds() * toneRate)
+	gapLen := int(gap.Seconds() * toneRate)
+	fadeLen := max(int(min(toneFade, pulse/2).Seconds()*toneRate), 1)
+
+	out := make([]byte, 0, (pulseLen+gapLen)*count*4)
+	for n := range count {
+		for i := range pulseLen {
+			envelope := math.Min(1, float64(min(i, pulseLen-i))/float64(fadeLen))
+			sample := uint16(int16(math.Sin(2*math.Pi*freq*float64(i)/toneRate) * envelope * math.MaxInt16))
+			out = binary.LittleEndian.AppendUint16(out, sample)
+			out = binary.LittleEndian.AppendUint16(out, sample)
+		}
+
+		if n < count-1 {
+			out = append(out, make([]byte, gapLen*4)...)
+		}
+	}
+
+	return pcmBuffer{bytes.NewReader(out)}
+}
+
+// chime synthesizes a bell-like tone that fades out, the octave adds some shimmer
+func chime(freq float64, length time.Duration) decoder {
+	samples := int(length.Seconds() * toneRate)
+	fadeLen := int(toneFade.Seconds() * toneRate)
+
+	out := make([]byte, 0, samples*4)
+	for i := range samples {
+		t := float64(i) / toneRate
+		envelope := math.Min(1, float64(i)/float64(fadeLen)) * math.Exp(-4*t/length.Seconds())
+		wave := 0.7*math.Sin(2*math.Pi*freq*t) + 0.3*math.Sin(4*math.Pi*freq*t)
+		sample := uint16(int16(wave * envelope * math.MaxInt16))
+		out = binary.LittleEndian.AppendUint16(out, sample)
+		out = binary.LittleEndian.AppendUint16(out, sample)
+	}
+
+	return pcmBuffer{bytes.NewReader(out)}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Warning is a point before the end of a timer (or of every phase of a sequence),
+// either the time left or the share of the duration elapsed
+type Warning struct {
+	Left    time.Duration `json:"left,omitempty"`
+	Percent float64       `json:"percent,omitempty"`
+	Notify  bool          `json:"notify"`
+	Sound   bool          `json:"sound"`
+}
+
+// ParseWarnings understands comma separated points like "5m, 1m sound, 90% notify".
+// A point both notifies and plays the warning sound unless told otherwise.
+func ParseWarnings(value string) ([]Warning, error) {
+	var warnings []Warning
+	for _, point := range strings.Split(value, ",") {
+		fields := strings.Fields(point)
+		if len(fields) == 0 {
+			continue
+		}
+
+		warning := Warning{Notify: true, Sound: true}
+		if len(fields) > 1 {
+			switch fields[len(fields)-1] {
+			case "notify":
+				warning.Sound = false
+				fields = fields[:len(fields)-1]
+			case "sound":
+				warning.Notify = false
+				fields = fields[:len(fields)-1]
+			}
+		}
+
+		at := strings.Join(fields, " ")
+		if num, ok := strings.CutSuffix(at, "%"); ok {
+			percent, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+			if err != nil || percent <= 0 || percent >= 100 {
+				return nil, fmt.Errorf("warning %q: expected a percentage between 0 and 100", point)
+			}
+
+			warning.Percent = percent
+		} else {
+			left, err := ParseDuration(at)
+			if err != nil || left < time.Second {
+				return nil, fmt.Errorf("warning %q: expected the time left, e.g. 5m, or a percentage, e.g. 90%%", point)
+			}
+
+			warning.Left = left.Round(time.Second)
+		}
+
+		warnings = append(warnings, warning)
+	}
+
+	return warnings, nil
+}
+
+// FormatWarnings is the reverse of ParseWarnings
+func FormatWarnings(warnings []Warning) string {
+	points := make([]string, len(warnings))
+	for i, warning := range warnings {
+		points[i] = warning.String()
+		switch {
+		case !warning.Sound:
+			points[i] += " notify"
+		case !warning.Notify:
+			points[i] += " sound"
+		}
+	}
+
+	return strings.Join(points, ", ")
+}
+
+func (w Warning) String() string {
+	if w.Percent > 0 {
+		return strconv.FormatFloat(w.Percent, 'f', -1, 64) + "%"
+	}
+
+	return FormatDuration(w.Left)
+}
+
+// at is the position of the point within the given duration
+func (w Warning) at(duration time.Duration) time.Duration {
+	if w.Percent > 0 {
+		return time.Duration(float64(duration) * w.Percent / 100)
+	}
+
+	return duration - w.Left
+}
+
+func (w Warning) crossed(elapsed time.Duration, duration time.Duration) bool {
+	return elapsed >= w.at(duration)
+}
+
+// armWarnings is called whenever the timer (or a phase) starts,
+// the points that are already behind are not announced
+func (p *TimerPlayer) armWarnings() {
+	p.warnedMu.Lock()
+	defer p.warnedMu.Unlock()
+
+	elapsed := p.elapsed()
+	p.warned = make([]bool, len(p.Options.Warnings))
+	for i, warning := range p.Options.Warnings {
+		p.warned[i] = warning.crossed(elapsed, p.duration)
+	}
+}
+
+// rearmWarnings is called when the position or the duration change,
+// the points that are ahead again are announced once more, e.g. from D-Bus while ticking
+func (p *TimerPlayer) rearmWarnings() {
+	p.warnedMu.Lock()
+	defer p.warnedMu.Unlock()
+
+	elapsed := p.elapsed()
+	for i, warning := range p.Options.Warnings {
+		p.warned[i] = p.warned[i] && warning.crossed(elapsed, p.duration)
+	}
+}
+
+// checkWarnings announces the points crossed since the last tick,
+// only the closest to the end if there are several
+func (p *TimerPlayer) checkWarnings(elapsed time.Duration) {
+	var crossed *Warning
+	p.warnedMu.Lock()
+	for i, warning := range p.Options.Warnings {
+		if p.warned[i] || !warning.crossed(elapsed, p.duration) {
+			continue
+		}
+
+		p.warned[i] = true
+		if crossed == nil || warning.at(p.duration) > crossed.at(p.duration) {
+			crossed = &p.Options.Warnings[i]
+		}
+	}
+	p.warnedMu.Unlock()
+
+	if crossed == nil {
+		return
+	}
+
+	left := p.duration - elapsed
+	log.Printf("timer %s: warning at %s, %s left", p.Id, crossed, FormatDuration(left))
+	p.emitControl("Warning", p.Id, int64(left.Round(time.Second)/time.Second))
+	if p.OnWarning != nil {
+		go p.OnWarning(p, *crossed, left)
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseWarnings(t *testing.T) {
+	tests := []struct {
+		value     string
+		formatted string
+		fails     bool
+	}{
+		{value: "5m", formatted: "05:00"},
+		{value: "5m, 1m sound, 90% notify", formatted: "05:00, 01:00 sound, 90% notify"},
+		{value: "1h 30 min notify, 12.5%", formatted: "01:30:00 notify, 12.5%"},
+		{value: " 30s ,, 2:00 ", formatted: "00:30, 02:00"},
+		{value: "", formatted: ""},
+		{value: "0s", fails: true},
+		{value: "0%", fails: true},
+		{value: "100%", fails: true},
+		{value: "five minutes", fails: true},
+		{value: "5m loud", fails: true},
+		{value: "notify", fails: true},
+	}
+
+	for _, test := range tests {
+		warnings, err := ParseWarnings(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("ParseWarnings(%q) = %v, expected an error", test.value, warnings)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseWarnings(%q): %v", test.value, err)
+			continue
+		}
+
+		formatted := FormatWarnings(warnings)
+		if formatted != test.formatted {
+			t.Errorf("FormatWarnings(ParseWarnings(%q)) = %q, expected %q", test.value, formatted, test.formatted)
+		}
+
+		again, err := ParseWarnings(formatted)
+		if err != nil || !slices.Equal(again, warnings) {
+			t.Errorf("ParseWarnings(%q) = %v, %v, expected %v", formatted, again, err, warnings)
+		}
+	}
+}
+
+func TestWarningCrossed(t *testing.T) {
+	warnings, err := ParseWarnings("1m, 90%")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		elapsed  time.Duration
+		expected []bool
+	}{
+		{elapsed: 0, expected: []bool{false, false}},
+		{elapsed: 8 * time.Minute, expected: []bool{false, false}},
+		{elapsed: 9 * time.Minute, expected: []bool{true, true}},
+		{elapsed: 9*time.Minute + 30*time.Second, expected: []bool{true, true}},
+	}
+
+	for _, test := range tests {
+		for i, warning := range warnings {
+			if crossed := warning.crossed(test.elapsed, 10*time.Minute); crossed != test.expected[i] {
+				t.Errorf("%s crossed at %s = %v, expected %v", warning, test.elapsed, crossed, test.expected[i])
+			}
+		}
+	}
+}
+
+// rearmWarnings may come from D-Bus while the ticker checks them, see -race
+func TestWarningsConcurrentRearm(t *testing.T) {
+	p := &TimerPlayer{duration: time.Hour, startTime: time.Now()}
+	p.Options.Warnings, _ = ParseWarnings("5m, 1m")
+	p.armWarnings()
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 1000 {
+			p.rearmWarnings()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 1000 {
+			p.checkWarnings(time.Minute)
+		}
+	}()
+	wg.Wait()
+}
@@ -5,20 +5,46 @@ import (
 	"github.com/diamondburned/gotk4/pkg/gio/v2"
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/efogdev/gotk4-adwaita/pkg/adw"
+	"github.com/godbus/dbus/v5"
 	"github.com/google/uuid"
 	"log"
 	"mpris-timer/internal/core"
+	"time"
 )
 
-func Notify(title string, text string) {
+const (
+	notificationsName  = "org.freedesktop.Notifications"
+	notificationsPath  = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notificationsIface = "org.freedesktop.Notifications"
+
+	// answerTimeout is how long a notification with actions waits to be answered
+	answerTimeout = 30 * time.Minute
+)
+
+// Urgency is the urgency level of the notification spec, a critical one stays on screen
+// and shows through Do Not Disturb
+type Urgency byte
+
+const (
+	UrgencyNormal   Urgency = 1
+	UrgencyCritical Urgency = 2
+)
+
+// NotifyAction is a button of the notification
+type NotifyAction struct {
+	Id    string
+	Label string
+}
+
+func Notify(title string, text string, urgency Urgency) {
 	log.Printf("notify: %s", title)
 
 	if !core.Overrides.UseUI {
-		sendNotification(core.App, title, text)
+		sendNotification(core.App, title, text, urgency)
 	} else {
 		nApp := adw.NewApplication(core.AppId, gio.ApplicationNonUnique)
 		nApp.ConnectActivate(func() {
-			sendNotification(nApp, title, text)
+			sendNotification(nApp, title, text, urgency)
 		})
 
 		_ = nApp.Register(context.Background())
@@ -26,16 +52,98 @@ func Notify(title string, text string) {
 	}
 }
 
-func sendNotification(app *adw.Application, title string, text string) {
+func sendNotification(app *adw.Application, title string, text string, urgency Urgency) {
 	id, _ := uuid.NewV7()
 	actionName := "app." + id.String()
 	app.AddAction(gio.NewSimpleAction(actionName, nil))
 
 	n := gio.NewNotification(title)
 	n.SetBody(text)
-	n.SetPriority(gio.NotificationPriorityUrgent)
+	n.SetPriority(gio.NotificationPriorityNormal)
+	if urgency == UrgencyCritical {
+		n.SetPriority(gio.NotificationPriorityUrgent)
+	}
 	n.SetDefaultAction(actionName)
 	n.SetIcon(gio.NewBytesIcon(glib.NewBytes(icon)))
 
 	app.SendNotification(id.String(), n)
 }
+
+// NotifyWithActions talks to org.freedesktop.Notifications directly, so that the buttons
+// work without a running GTK app. Blocks until the notification is answered or closed,
+// returns the id of the clicked action, or an empty string if there was none.
+func NotifyWithActions(title string, text string, urgency Urgency, actions []NotifyAction) string {
+	log.Printf("notify: %s, %d actions", title, len(actions))
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		log.Printf("connect to session bus: %v", err)
+		Notify(title, text, urgency)
+		return ""
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchInterface(notificationsIface),
+		dbus.WithMatchObjectPath(notificationsPath),
+	}
+
+	if err = conn.AddMatchSignal(match...); err != nil {
+		log.Printf("subscribe to notification signals: %v", err)
+	}
+	defer func() { _ = conn.RemoveMatchSignal(match...) }()
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	buttons := make([]string, 0, len(actions)*2)
+	for _, action := range actions {
+		buttons = append(buttons, action.Id, action.Label)
+	}
+
+	hints := map[string]dbus.Variant{
+		"urgency":       dbus.MakeVariant(byte(urgency)),
+		"desktop-entry": dbus.MakeVariant(core.AppId),
+	}
+
+	var id uint32
+	err = conn.Object(notificationsName, notificationsPath).
+		Call(notificationsIface+".Notify", 0, core.AppName, uint32(0), core.AppId, title, text, buttons, hints, int32(0)).
+		Store(&id)
+	if err != nil {
+		log.Printf("send notification: %v", err)
+		Notify(title, text, urgency)
+		return ""
+	}
+
+	timeout := time.After(answerTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if len(sig.Body) < 2 || sig.Body[0] != id {
+				continue
+			}
+
+			switch sig.Name {
+			case notificationsIface + ".ActionInvoked":
+				closeNotification(conn, id)
+				if action, _ := sig.Body[1].(string); action != "default" {
+					return action
+				}
+				return ""
+			case notificationsIface + ".NotificationClosed":
+				return ""
+			}
+		case <-timeout:
+			closeNotification(conn, id)
+			return ""
+		}
+	}
+}
+
+func closeNotification(conn *dbus.Conn, id uint32) {
+	err := conn.Object(notificationsName, notificationsPath).Call(notificationsIface+".CloseNotification", 0, id).Err
+	if err != nil {
+		log.Printf("close notification: %v", err)
+	}
+}
@@ -9,9 +9,9 @@ import (
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 	"github.com/efogdev/gotk4-adwaita/pkg/adw"
 	"log"
+	"math"
 	"mpris-timer/internal/core"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -191,12 +191,21 @@ func populateInterfaceGroup(group *adw.PreferencesGroup) {
 		core.SetForceTrayIcon(forceTraySwitch.Active())
 	})
 
+	cacheSizeRow := adw.NewSpinRowWithRange(0, 1024, 16)
+	cacheSizeRow.SetTitle("Image cache limit, MB")
+	cacheSizeRow.SetSubtitle("0 for no limit")
+	cacheSizeRow.SetValue(float64(core.UserPrefs.CacheSizeMB))
+	cacheSizeRow.Connect("notify::value", func() {
+		core.SetCacheSizeMB(uint(cacheSizeRow.Value()))
+	})
+
 	group.Add(titleEntry)
 	group.Add(titleSwitch)
 	group.Add(showPresetsSwitch)
 	group.Add(presetsOnRightSwitch)
 	group.Add(winSizeSwitch)
 	group.Add(forceTraySwitch)
+	group.Add(cacheSizeRow)
 }
 
 func populateTimerGroup(group *adw.PreferencesGroup) {
@@ -204,6 +213,19 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 	volumeRow := adw.NewActionRow()
 	volumeSlider := gtk.NewScaleWithRange(gtk.OrientationHorizontal, 0, 100, 1)
 	customSoundSwitch := adw.NewSwitchRow()
+	soundNameEntry := adw.NewEntryRow()
+	ringSwitch := adw.NewSwitchRow()
+	crescendoSwitch := adw.NewSwitchRow()
+	maxRingRow := adw.NewSpinRowWithRange(1, 60, 1)
+	sinkSelect := adw.NewComboRow()
+	fadeRow := adw.NewSpinRowWithRange(0, 60, 1)
+	fadeFromRow := adw.NewSpinRowWithRange(0, 100, 5)
+	tickSwitch := adw.NewSwitchRow()
+	chimeSwitch := adw.NewSwitchRow()
+	countdownRow := adw.NewSpinRowWithRange(0, 10, 1)
+	tickVolumeRow := newCueVolumeRow("Tick volume", core.Overrides.TickVolume, core.SetTickVolume, core.PreviewTick)
+	chimeVolumeRow := newCueVolumeRow("Chime volume", core.Overrides.ChimeVolume, core.SetChimeVolume, core.PreviewChime)
+	countdownVolumeRow := newCueVolumeRow("Countdown volume", core.Overrides.CountdownVolume, core.SetCountdownVolume, core.PreviewCountdown)
 
 	soundSwitch := adw.NewSwitchRow()
 	soundSwitch.SetTitle("Enable sound")
@@ -211,8 +233,13 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 	soundSwitch.SetActive(core.UserPrefs.EnableSound)
 	soundSwitch.Connect("notify::active", func() {
 		core.SetEnableSound(soundSwitch.Active())
-		volumeRow.SetVisible(core.UserPrefs.EnableSound)
 		customSoundSwitch.SetVisible(core.UserPrefs.EnableSound)
+		soundNameEntry.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.SoundFilename == "")
+		ringSwitch.SetVisible(core.UserPrefs.EnableSound)
+		crescendoSwitch.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.RingUntilDismissed)
+		maxRingRow.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.RingUntilDismissed)
+		fadeRow.SetVisible(core.UserPrefs.EnableSound)
+		fadeFromRow.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.FadeSeconds > 0)
 	})
 
 	customSoundSwitch = adw.NewSwitchRow()
@@ -220,16 +247,22 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 	customSoundSwitch.SetVisible(core.UserPrefs.EnableSound)
 	customSoundSwitch.SetActive(core.UserPrefs.SoundFilename == "")
 	customSoundSwitch.Connect("notify::active", func() {
+		soundNameEntry.SetVisible(customSoundSwitch.Active())
 		if customSoundSwitch.Active() {
 			core.SetSoundFilename("")
 		} else {
 			dialog := gtk.NewFileDialog()
 			dialog.SetModal(true)
-			dialog.SetTitle("MP3 sound")
+			dialog.SetTitle("Sound")
 
 			filter := gtk.NewFileFilter()
-			filter.AddSuffix("mp3")
-			filter.AddMIMEType("audio/mpeg")
+			filter.SetName("MP3, Ogg, FLAC or WAV")
+			for _, suffix := range []string{"mp3", "ogg", "oga", "flac", "wav"} {
+				filter.AddSuffix(suffix)
+			}
+			for _, mime := range []string{"audio/mpeg", "audio/ogg", "audio/x-vorbis+ogg", "audio/flac", "audio/x-flac", "audio/wav", "audio/x-wav"} {
+				filter.AddMIMEType(mime)
+			}
 
 			model := gioutil.NewListModel[*gtk.FileFilter]()
 			model.Append(filter)
@@ -240,12 +273,34 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 				file, err := dialog.OpenFinish(r)
 				if err == nil {
 					core.SetSoundFilename(file.Path())
+					if core.UserPrefs.SoundFilename == "" {
+						// not a supported sound, back to the default one
+						customSoundSwitch.SetActive(true)
+						return
+					}
+
 					_ = core.PlaySound()
 				}
 			})
 		}
 	})
 
+	// a name of the desktop's sound theme, used instead of the embedded sound
+	soundNameEntry.SetTitle("Theme sound, e.g. complete")
+	soundNameEntry.SetText(core.UserPrefs.SoundName)
+	soundNameEntry.SetShowApplyButton(true)
+	soundNameEntry.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.SoundFilename == "")
+	soundNameEntry.ConnectApply(func() {
+		core.SetSoundName(strings.TrimSpace(soundNameEntry.Text()))
+		if core.UserPrefs.SoundName == "" {
+			// not found in the theme, back to the embedded sound
+			soundNameEntry.SetText("")
+			return
+		}
+
+		go func() { _ = core.PlaySound() }()
+	})
+
 	volumePreviewCtrl := gtk.NewGestureClick()
 	volumePreviewCtrl.SetPropagationPhase(gtk.PhaseCapture)
 	volumePreviewCtrl.ConnectReleased(func(_ int, _ float64, _ float64) {
@@ -254,7 +309,6 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 
 	volumeRow.SetTitle("Sound volume")
 	volumeRow.SetSubtitle(fmt.Sprintf("%v%%", int(core.Overrides.Volume*100)))
-	volumeRow.SetVisible(core.UserPrefs.EnableSound)
 	volumeRow.AddSuffix(volumeSlider)
 	volumeRow.AddController(volumePreviewCtrl)
 
@@ -272,6 +326,67 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 		return false
 	})
 
+	fadeRow.SetTitle("Fade in, seconds")
+	fadeRow.SetSubtitle("Up to the sound volume, 0 to turn off")
+	fadeRow.SetValue(float64(min(core.UserPrefs.FadeSeconds, 60)))
+	fadeRow.SetVisible(core.UserPrefs.EnableSound)
+	fadeRow.Connect("notify::value", func() {
+		core.SetFadeSeconds(uint(fadeRow.Value()))
+		fadeFromRow.SetVisible(core.UserPrefs.FadeSeconds > 0)
+	})
+
+	fadeFromRow.SetTitle("Fade in from, %")
+	fadeFromRow.SetValue(math.Round(core.UserPrefs.FadeFrom * 100))
+	fadeFromRow.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.FadeSeconds > 0)
+	fadeFromRow.Connect("notify::value", func() {
+		core.SetFadeFrom(fadeFromRow.Value() / 100)
+	})
+
+	// the saved sink is kept even if it's unplugged now
+	sinks := append([]core.AudioSink{{Description: "Default"}}, core.ListAudioSinks()...)
+	if core.UserPrefs.AudioSink != "" && !slices.ContainsFunc(sinks, func(sink core.AudioSink) bool { return sink.Name == core.UserPrefs.AudioSink }) {
+		sinks = append(sinks, core.AudioSink{Name: core.UserPrefs.AudioSink, Description: core.UserPrefs.AudioSink})
+	}
+
+	sinkNames := make([]string, len(sinks))
+	selectedSink := 0
+	for i, sink := range sinks {
+		sinkNames[i] = sink.Description
+		if sink.Name == core.UserPrefs.AudioSink {
+			selectedSink = i
+		}
+	}
+
+	sinkSelect.SetTitle("Output device")
+	sinkSelect.SetSubtitle("Requires restart")
+	sinkSelect.SetModel(gtk.NewStringList(sinkNames))
+	sinkSelect.SetSelected(uint(selectedSink))
+	sinkSelect.Connect("notify::selected", func() {
+		core.SetAudioSink(sinks[sinkSelect.Selected()].Name)
+	})
+
+	// the cues play with the alarm sound off as well, one a second, the countdown first
+	tickSwitch.SetTitle("Tick every second")
+	tickSwitch.SetSubtitle("Not during the countdown or a chime")
+	tickSwitch.SetActive(core.UserPrefs.Tick)
+	tickSwitch.Connect("notify::active", func() {
+		core.SetTick(tickSwitch.Active())
+	})
+
+	chimeSwitch.SetTitle("Chime every minute")
+	chimeSwitch.SetSubtitle("Not during the countdown")
+	chimeSwitch.SetActive(core.UserPrefs.Chime)
+	chimeSwitch.Connect("notify::active", func() {
+		core.SetChime(chimeSwitch.Active())
+	})
+
+	countdownRow.SetTitle("Count down, seconds")
+	countdownRow.SetSubtitle("Samples from the countdown folder, or beeps")
+	countdownRow.SetValue(float64(min(core.UserPrefs.Countdown, 10)))
+	countdownRow.Connect("notify::value", func() {
+		core.SetCountdown(uint(countdownRow.Value()))
+	})
+
 	notificationSwitch := adw.NewSwitchRow()
 	notificationSwitch.SetTitle("Enable notification")
 	notificationSwitch.SetActive(core.UserPrefs.ShouldNotify)
@@ -287,11 +402,122 @@ func populateTimerGroup(group *adw.PreferencesGroup) {
 		core.SetDefaultText(textEntry.Text())
 	})
 
+	suspendSwitch := adw.NewSwitchRow()
+	suspendSwitch.SetTitle("Count time in suspend")
+	suspendSwitch.SetSubtitle("Alarms always do")
+	suspendSwitch.SetActive(core.UserPrefs.CountSuspend)
+	suspendSwitch.Connect("notify::active", func() {
+		core.SetCountSuspend(suspendSwitch.Active())
+	})
+
+	ringSwitch.SetTitle("Ring until dismissed")
+	ringSwitch.SetSubtitle("Repeat the sound until the notification is answered")
+	ringSwitch.SetActive(core.UserPrefs.RingUntilDismissed)
+	ringSwitch.SetVisible(core.UserPrefs.EnableSound)
+	ringSwitch.Connect("notify::active", func() {
+		core.SetRingUntilDismissed(ringSwitch.Active())
+		crescendoSwitch.SetVisible(core.UserPrefs.RingUntilDismissed)
+		maxRingRow.SetVisible(core.UserPrefs.RingUntilDismissed)
+	})
+
+	crescendoSwitch.SetTitle("Get louder")
+	crescendoSwitch.SetSubtitle("Up to the volume above")
+	crescendoSwitch.SetActive(core.UserPrefs.Crescendo)
+	crescendoSwitch.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.RingUntilDismissed)
+	crescendoSwitch.Connect("notify::active", func() {
+		core.SetCrescendo(crescendoSwitch.Active())
+	})
+
+	maxRingRow.SetTitle("Ring at most, minutes")
+	maxRingRow.SetValue(float64(max(core.UserPrefs.MaxRingMinutes, 1)))
+	maxRingRow.SetVisible(core.UserPrefs.EnableSound && core.UserPrefs.RingUntilDismissed)
+	maxRingRow.Connect("notify::value", func() {
+		core.SetMaxRingMinutes(uint(maxRingRow.Value()))
+	})
+
+	warningsEntry := adw.NewEntryRow()
+	warningsEntry.SetTitle("Warnings, e.g. 5m, 1m sound, 90% notify")
+	warningsEntry.SetText(core.UserPrefs.Warnings)
+	warningsEntry.SetShowApplyButton(true)
+	warningsEntry.ConnectApply(func() {
+		if _, err := core.ParseWarnings(warningsEntry.Text()); err != nil {
+			warningsEntry.AddCSSClass("error")
+			return
+		}
+
+		warningsEntry.RemoveCSSClass("error")
+		core.SetWarnings(warningsEntry.Text())
+	})
+
+	restoreSwitch := adw.NewSwitchRow()
+	restoreSwitch.SetTitle("Restore unfinished timers")
+	restoreSwitch.SetSubtitle("After a crash or a logout")
+	restoreSwitch.SetActive(core.UserPrefs.RestoreTimers)
+	restoreSwitch.Connect("notify::active", func() {
+		core.SetRestoreTimers(restoreSwitch.Active())
+	})
+
+	snoozeRow := adw.NewSpinRowWithRange(1, 120, 1)
+	snoozeRow.SetTitle("Snooze, minutes")
+	snoozeRow.SetSubtitle("Offered by the finish notification")
+	snoozeRow.SetValue(float64(max(core.UserPrefs.SnoozeMinutes, 1)))
+	snoozeRow.Connect("notify::value", func() {
+		core.SetSnoozeMinutes(uint(snoozeRow.Value()))
+	})
+
 	group.Add(soundSwitch)
 	group.Add(customSoundSwitch)
+	group.Add(soundNameEntry)
 	group.Add(volumeRow)
+	group.Add(fadeRow)
+	group.Add(fadeFromRow)
+	group.Add(sinkSelect)
+	group.Add(ringSwitch)
+	group.Add(crescendoSwitch)
+	group.Add(maxRingRow)
+	group.Add(tickSwitch)
+	group.Add(chimeSwitch)
+	group.Add(countdownRow)
+	group.Add(tickVolumeRow)
+	group.Add(chimeVolumeRow)
+	group.Add(countdownVolumeRow)
 	group.Add(notificationSwitch)
 	group.Add(textEntry)
+	group.Add(snoozeRow)
+	group.Add(warningsEntry)
+	group.Add(suspendSwitch)
+	group.Add(restoreSwitch)
+}
+
+// newCueVolumeRow is a slider of the volume of a cue, relative to the sound volume, clicking it plays the cue
+func newCueVolumeRow(title string, volume float64, set func(float64), preview func()) *adw.ActionRow {
+	previewCtrl := gtk.NewGestureClick()
+	previewCtrl.SetPropagationPhase(gtk.PhaseCapture)
+	previewCtrl.ConnectReleased(func(_ int, _ float64, _ float64) {
+		go preview()
+	})
+
+	slider := gtk.NewScaleWithRange(gtk.OrientationHorizontal, 0, 100, 1)
+	row := adw.NewActionRow()
+	row.SetTitle(title)
+	row.SetSubtitle(fmt.Sprintf("%v%% of the sound volume", int(volume*100)))
+	row.AddSuffix(slider)
+	row.AddController(previewCtrl)
+
+	slider.SetValue(volume * 100)
+	slider.SetSizeRequest(sliderWidth, 0)
+	slider.ConnectChangeValue(func(scroll gtk.ScrollType, value float64) (ok bool) {
+		if value > 100 {
+			value = 100
+			slider.SetValue(value)
+		}
+
+		set(value / 100)
+		row.SetSubtitle(fmt.Sprintf("%v%% of the sound volume", int(value)))
+		return false
+	})
+
+	return row
 }
 
 func populateVisualsGroup(group *adw.PreferencesGroup) {
@@ -314,6 +540,19 @@ func populateVisualsGroup(group *adw.PreferencesGroup) {
 		core.SetProgressColor(core.HexFromRGBA(colorSwitch.RGBA()))
 	})
 
+	styleNames := make([]string, len(core.Styles))
+	for i, style := range core.Styles {
+		styleNames[i] = strings.ToUpper(style[:1]) + style[1:]
+	}
+
+	styleSelect := adw.NewComboRow()
+	styleSelect.SetTitle("Style")
+	styleSelect.SetModel(gtk.NewStringList(styleNames))
+	styleSelect.SetSelected(uint(max(slices.Index(core.Styles, core.Overrides.Style), 0)))
+	styleSelect.Connect("notify::selected", func() {
+		core.SetStyle(core.Styles[styleSelect.Selected()])
+	})
+
 	roundedSwitch := adw.NewSwitchRow()
 	roundedSwitch.SetTitle("Rounded corners")
 	roundedSwitch.SetActive(core.UserPrefs.Rounded)
@@ -339,6 +578,7 @@ func populateVisualsGroup(group *adw.PreferencesGroup) {
 	})
 
 	group.Add(colorRow)
+	group.Add(styleSelect)
 	group.Add(roundedSwitch)
 
 	if core.IsGnome || core.IsPlasma {
@@ -472,36 +712,15 @@ func RenderPresets(toAdd []string) {
 		}
 
 		formatTitle := func() {
-			parts := strings.Split(title.Text(), ":")
-			if len(parts) < 1 && len(parts) > 3 {
+			// anything ParseDuration accepts is stored as MM:SS or HH:MM:SS
+			t, err := core.TimeFromPreset(title.Text())
+			if err != nil {
+				log.Printf("format preset: %v", err)
 				cleanTitle()
 				return
 			}
 
-			var vals []int
-			for _, v := range parts {
-				val, err := strconv.Atoi(v)
-				if err != nil || val < 0 || val > 59 {
-					cleanTitle()
-					return
-				}
-
-				vals = append(vals, val)
-			}
-
-			var newText string
-			if len(vals) == 1 {
-				newText = fmt.Sprintf("00:%02d", vals[0])
-			} else if len(vals) == 2 {
-				newText = fmt.Sprintf("%02d:%02d", vals[0], vals[1])
-			} else if len(vals) == 3 {
-				newText = fmt.Sprintf("%02d:%02d:%02d", vals[0], vals[1], vals[2])
-			}
-
-			if newText == "" {
-				cleanTitle()
-				return
-			}
+			newText := core.FormatDuration(t.Sub(time.Time{}))
 
 			var presets []string
 			for _idx, p := range core.UserPrefs.Presets {
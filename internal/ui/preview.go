@@ -1,11 +1,13 @@
 package ui
 
 import (
-	"log"
+	"fmt"
+	"math"
 	"mpris-timer/internal/core"
 	"time"
 
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
@@ -15,6 +17,7 @@ import (
 //   - occasional freezes, crashes, low (2-3) fps
 func renderPreview(box *gtk.Image) {
 	tickFor := time.Second * 5                 // 5 seconds timer
+	shownFor := time.Minute * 5                // the segments and digits show a 5 minutes one
 	ticker := time.NewTicker(time.Second / 30) // 30 base fps
 	defer ticker.Stop()
 
@@ -30,7 +33,8 @@ func renderPreview(box *gtk.Image) {
 	}()
 
 	// memory leak is terrible w/o this
-	imgCache := make(map[string]*gdk.Paintable)
+	imgCache := make(map[string]*gdk.MemoryTexture)
+	size := 128 * box.ScaleFactor()
 	timeStart := time.Now()
 	for range ticker.C {
 		if prefsWin == nil || box == nil || !prefsWin.IsVisible() {
@@ -44,34 +48,25 @@ func renderPreview(box *gtk.Image) {
 			continue
 		}
 
-		imgFilename, err := core.MakeProgressCircle(percent)
-		if err != nil {
-			log.Printf("render preview: %v", err)
-			continue
+		// same steps as the files of MakeProgressCircle
+		percent = math.Round(percent*100) / 100
+		frame := core.Frame{
+			Progress: percent,
+			Left:     (shownFor - time.Duration(float64(shownFor)*percent/100)).Round(time.Second),
+			Duration: shownFor,
+			Title:    core.Overrides.Title,
 		}
 
-		cached := imgCache[imgFilename]
+		key := fmt.Sprintf("%s.%s.sh%v.r%v.%.2f", core.Overrides.Style, core.Overrides.Color, core.Overrides.HasShadow, core.Overrides.Rounded, percent)
+		cached := imgCache[key]
 		if cached != nil {
 			box.SetFromPaintable(cached)
 			continue
 		}
 
-		img := gtk.NewImageFromFile(imgFilename)
-		if img == nil {
-			continue
-		}
-
-		paintable := img.Paintable()
-		if paintable == nil {
-			continue
-		}
-
-		curImg := paintable.CurrentImage()
-		if curImg == nil {
-			continue
-		}
-
-		imgCache[imgFilename] = curImg
-		box.SetFromPaintable(curImg)
+		img := core.RenderProgress(frame, size)
+		texture := gdk.NewMemoryTexture(size, size, gdk.MemoryR8G8B8A8Premultiplied, glib.NewBytes(img.Pix), uint(img.Stride))
+		imgCache[key] = texture
+		box.SetFromPaintable(texture)
 	}
 }
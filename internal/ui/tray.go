@@ -5,7 +5,8 @@ import (
 	"fyne.io/systray"
 	"log"
 	"mpris-timer/internal/core"
-	"os"
+	"sync"
+	"time"
 )
 
 var (
@@ -13,7 +14,15 @@ var (
 	progress *systray.MenuItem
 	restart  *systray.MenuItem
 	play     *systray.MenuItem
+	lap      *systray.MenuItem
+	addOne   *systray.MenuItem
+	subOne   *systray.MenuItem
+	addFive  *systray.MenuItem
 	quit     *systray.MenuItem
+
+	// iconKey is the core.FrameKey of the icon shown
+	iconKey string
+	iconMu  sync.Mutex
 )
 
 func CreateTrayIcon(timer *core.TimerPlayer) {
@@ -31,6 +40,9 @@ func CreateTrayIcon(timer *core.TimerPlayer) {
 
 	initCh := make(chan struct{})
 	trayIcon = true
+	iconMu.Lock()
+	iconKey = ""
+	iconMu.Unlock()
 	timer.AddSubscription(func(event core.PropsChangedEvent) {
 		if !timer.IsFinished {
 			go updateTray(event)
@@ -44,7 +56,19 @@ func CreateTrayIcon(timer *core.TimerPlayer) {
 		progress = systray.AddMenuItem("Not active", "Current progress")
 		systray.AddSeparator()
 		play = systray.AddMenuItem("Continue", "Play/pause")
+		lap = systray.AddMenuItem("Lap", "Record a split")
 		restart = systray.AddMenuItem("Restart", "Restart timer")
+		addOne = systray.AddMenuItem("+1 min", "Add a minute")
+		subOne = systray.AddMenuItem("−1 min", "Remove a minute")
+		addFive = systray.AddMenuItem("+5 min", "Add 5 minutes")
+		if timer.Options.Stopwatch {
+			restart.SetTitle("Reset")
+			addOne.Hide()
+			subOne.Hide()
+			addFive.Hide()
+		} else {
+			lap.Hide()
+		}
 		quit = systray.AddMenuItem("Quit", "Stop timer and quit")
 		initCh <- struct{}{}
 	}, func() {
@@ -55,11 +79,19 @@ func CreateTrayIcon(timer *core.TimerPlayer) {
 	for {
 		select {
 		case <-quit.ClickedCh:
-			os.Exit(0)
+			_ = timer.Stop()
 		case <-restart.ClickedCh:
 			_ = timer.Previous()
 		case <-play.ClickedCh:
 			_ = timer.PlayPause()
+		case <-lap.ClickedCh:
+			_ = timer.Lap()
+		case <-addOne.ClickedCh:
+			_ = timer.AddTime(time.Minute)
+		case <-subOne.ClickedCh:
+			_ = timer.AddTime(-time.Minute)
+		case <-addFive.ClickedCh:
+			_ = timer.AddTime(5 * time.Minute)
 		}
 	}
 }
@@ -80,12 +112,17 @@ func updateTray(event core.PropsChangedEvent) {
 		play.SetTitle("Pause")
 	}
 
-	if event.Img != "" {
-		iconBytes, err := core.Pngify(event.Img)
+	// most events don't move the icon by a 0.01% step
+	key := core.FrameKey(event.Frame)
+	iconMu.Lock()
+	if key != iconKey {
+		iconBytes, err := core.TrayIconPNG(event.Frame)
 		if err == nil {
+			iconKey = key
 			systray.SetIcon(iconBytes)
 		}
 	}
+	iconMu.Unlock()
 
 	progress.SetTitle(fmt.Sprintf("%s: %s", core.Overrides.Title, event.Text))
 }
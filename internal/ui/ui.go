@@ -2,10 +2,13 @@ package ui
 
 import (
 	_ "embed"
+	"fmt"
 	"log"
 	"mpris-timer/internal/core"
 	"os"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
@@ -40,6 +43,8 @@ var (
 	minLabel      *gtk.Entry
 	secLabel      *gtk.Entry
 	titleLabel    *gtk.Entry
+	durationEntry *gtk.Entry
+	alarmBtn      *gtk.ToggleButton
 	flowBox       *gtk.FlowBox
 	initComplete  bool
 )
@@ -175,7 +180,11 @@ func NewSidebar() *adw.NavigationPage {
 		flowBox.Append(label)
 
 		onActivate := func() {
-			time := core.TimeFromPreset(preset)
+			time, err := core.TimeFromPreset(preset)
+			if err != nil {
+				log.Printf("activate preset: %v", err)
+				return
+			}
 
 			if hrsLabel == nil || minLabel == nil || secLabel == nil {
 				return
@@ -269,6 +278,14 @@ func NewContent() *adw.NavigationPage {
 
 	startFn := func() {
 		time := core.TimeFromStrings(hrsLabel.Text(), minLabel.Text(), secLabel.Text())
+		if alarmBtn.Active() {
+			core.Overrides.AlarmAt = core.AlarmFromParts(time.Hour(), time.Minute(), time.Second())
+			core.Overrides.Duration = core.SecondsUntil(core.Overrides.AlarmAt)
+			saveSize()
+			win.Close()
+			return
+		}
+
 		seconds := time.Hour()*60*60 + time.Minute()*60 + time.Second()
 		if seconds > 0 {
 			core.Overrides.Duration = seconds
@@ -324,6 +341,10 @@ func NewContent() *adw.NavigationPage {
 	titleBox.SetHExpand(true)
 	titleBox.Append(titleLabel)
 
+	if banner := newRestoreBanner(); banner != nil {
+		vBox.Append(banner)
+	}
+
 	if core.UserPrefs.ShowTitle {
 		vBox.Append(titleBox)
 	}
@@ -399,6 +420,48 @@ func NewContent() *adw.NavigationPage {
 	startBtn.ConnectActivate(startFn)
 	startBtn.AddController(leftKeyCtrl)
 
+	// alarm mode: the entries are the time of day to ring at
+	alarmBtnContent := adw.NewButtonContent()
+	alarmBtnContent.SetHExpand(false)
+	alarmBtnContent.SetLabel("")
+	alarmBtnContent.SetIconName("alarm-symbolic")
+
+	alarmBtn = gtk.NewToggleButton()
+	alarmBtn.SetTooltipText("Alarm: ring at this time of day")
+	alarmBtn.SetChild(alarmBtnContent)
+	alarmBtn.AddCSSClass("control-btn")
+	alarmBtn.AddCSSClass("prefs-btn")
+	alarmBtn.SetFocusable(false)
+	alarmBtn.ConnectToggled(func() {
+		if !alarmBtn.Active() {
+			btnContent.SetLabel("Start")
+			return
+		}
+
+		now := time.Now()
+		hrsLabel.SetText(core.NumToLabelText(now.Hour()))
+		minLabel.SetText(core.NumToLabelText(now.Minute()))
+		secLabel.SetText("00")
+		btnContent.SetLabel("Set alarm")
+	})
+
+	stopwatchBtnContent := adw.NewButtonContent()
+	stopwatchBtnContent.SetHExpand(false)
+	stopwatchBtnContent.SetLabel("")
+	stopwatchBtnContent.SetIconName("preferences-system-time-symbolic")
+
+	stopwatchBtn := gtk.NewButton()
+	stopwatchBtn.SetTooltipText("Stopwatch: count up")
+	stopwatchBtn.SetChild(stopwatchBtnContent)
+	stopwatchBtn.AddCSSClass("control-btn")
+	stopwatchBtn.AddCSSClass("prefs-btn")
+	stopwatchBtn.SetFocusable(false)
+	stopwatchBtn.ConnectClicked(func() {
+		core.Overrides.Stopwatch = true
+		saveSize()
+		win.Close()
+	})
+
 	prefsBtnContent := adw.NewButtonContent()
 	prefsBtnContent.SetHExpand(false)
 	prefsBtnContent.SetLabel("")
@@ -430,13 +493,58 @@ func NewContent() *adw.NavigationPage {
 		os.Exit(0)
 	})
 
+	// free text alternative to the entries above, e.g. "25m", "1h30m" or "25m work, 5m break ×4"
+	durationEntry = gtk.NewEntry()
+	durationEntry.SetPlaceholderText("25m, 1h30m…")
+	durationEntry.SetWidthChars(10)
+	durationEntry.SetMaxWidthChars(10)
+	durationEntry.SetVAlign(gtk.AlignCenter)
+	durationEntry.AddCSSClass("duration-entry")
+	durationEntry.ConnectChanged(func() {
+		durationEntry.RemoveCSSClass("error")
+		durationEntry.SetTooltipText("")
+	})
+	durationEntry.ConnectActivate(func() {
+		segments, seqErr := core.ParseSequence(durationEntry.Text())
+		if seqErr == nil && len(segments) > 1 {
+			core.Overrides.Sequence = segments
+			core.Overrides.Duration = int(core.TotalDuration(segments) / time.Second)
+			saveSize()
+			win.Close()
+			return
+		}
+
+		d, err := core.ParseDuration(durationEntry.Text())
+		seconds := int(d.Round(time.Second) / time.Second)
+		if err == nil && seconds <= 0 {
+			err = fmt.Errorf("duration must be positive")
+		}
+
+		if err != nil && strings.Contains(durationEntry.Text(), ",") {
+			err = seqErr
+		}
+
+		if err != nil {
+			durationEntry.AddCSSClass("error")
+			durationEntry.SetTooltipText(err.Error())
+			return
+		}
+
+		core.Overrides.Duration = seconds
+		saveSize()
+		win.Close()
+	})
+
 	footer := gtk.NewBox(gtk.OrientationHorizontal, 12)
 	footer.SetVAlign(gtk.AlignCenter)
 	footer.SetHAlign(gtk.AlignCenter)
 	footer.SetHExpand(false)
 	footer.SetMarginBottom(4)
 	footer.AddCSSClass("footer")
+	footer.Append(durationEntry)
 	footer.Append(startBtn)
+	footer.Append(alarmBtn)
+	footer.Append(stopwatchBtn)
 	footer.Append(prefsBtn)
 	footer.Append(exitBtn)
 	vBox.Append(footer)
@@ -444,6 +552,59 @@ func NewContent() *adw.NavigationPage {
 	return content
 }
 
+// newRestoreBanner offers the unfinished timers when they are not restored automatically,
+// by the process that holds the restore claim only
+func newRestoreBanner() *adw.Banner {
+	if core.UserPrefs.RestoreTimers || !core.ClaimRestore() {
+		return nil
+	}
+
+	pending := slices.DeleteFunc(core.PendingTimers(), core.SavedTimer.IsExpired)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	title := fmt.Sprintf("%s was not finished", pending[0].Title)
+	if len(pending) > 1 {
+		title = fmt.Sprintf("%d timers were not finished", len(pending))
+	}
+
+	banner := adw.NewBanner(title)
+	banner.SetButtonLabel("Restore…")
+	banner.SetRevealed(true)
+	banner.ConnectButtonClicked(func() {
+		dialog := adw.NewAlertDialog(title, "Continue where it left off, or discard the saved state for good.")
+		dialog.AddResponse("later", "Not Now")
+		dialog.AddResponse("discard", "Discard")
+		dialog.AddResponse("restore", "Restore")
+		dialog.SetResponseAppearance("discard", adw.ResponseDestructive)
+		dialog.SetResponseAppearance("restore", adw.ResponseSuggested)
+		dialog.SetDefaultResponse("restore")
+		dialog.SetCloseResponse("later")
+		dialog.ConnectResponse(func(response string) {
+			switch response {
+			case "restore":
+				for _, saved := range pending {
+					if err := core.RestoreDetached(saved.Id); err != nil {
+						log.Printf("restore timer %s: %v", saved.Id, err)
+					}
+				}
+			case "discard":
+				for _, saved := range pending {
+					core.ForgetTimer(saved.Id)
+				}
+			default:
+				return
+			}
+
+			banner.SetRevealed(false)
+		})
+		dialog.Present(banner)
+	})
+
+	return banner
+}
+
 func getMinHeight() int {
 	height := defaultMinHeight
 	if !core.UserPrefs.ShowTitle {